@@ -0,0 +1,455 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is Nagios plugin state, its value is the plugin exit code
+type State int
+
+// Nagios plugin states
+const (
+	StateOK       State = 0
+	StateWarning  State = 1
+	StateCritical State = 2
+	StateUnknown  State = 3
+)
+
+var stateNames = map[State]string{
+	StateOK:       "OK",
+	StateWarning:  "WARNING",
+	StateCritical: "CRITICAL",
+	StateUnknown:  "UNKNOWN",
+}
+
+// stateRank orders states from best to worst when several checks are combined
+var stateRank = map[State]int{
+	StateOK:       0,
+	StateUnknown:  1,
+	StateWarning:  2,
+	StateCritical: 3,
+}
+
+// String returns Nagios name of the state, eg. CRITICAL
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+//Worse returns the more severe of two states, CRITICAL > WARNING > UNKNOWN > OK
+func Worse(a State, b State) State {
+	if stateRank[b] > stateRank[a] {
+		return b
+	}
+	return a
+}
+
+// AccountState is state assigned to single account by a check, along with the reason
+type AccountState struct {
+	Check   string
+	Account Account
+	State   State
+	Reason  string
+}
+
+// CheckResult is outcome of a single check: its state, summary counting affected accounts,
+// the affected accounts, most severe first, and Nagios performance data of the check
+type CheckResult struct {
+	Name     string
+	State    State
+	Summary  string
+	Accounts []AccountState
+	Perfdata []string
+}
+
+// Check evaluates state of loaded accounts
+type Check interface {
+	Name() string
+	Evaluate(set *AccountSet) (CheckResult, error)
+}
+
+// Disabled reports disabled accounts, accounts in unknown state and accounts which were not found
+type Disabled struct{}
+
+// Locked reports locked accounts
+type Locked struct{}
+
+// Expired reports accounts which expire within Warning or Critical days
+type Expired struct {
+	Warning  int
+	Critical int
+}
+
+// PasswordExpiry reports accounts which password expires within Warning or Critical days
+type PasswordExpiry struct {
+	Warning  int
+	Critical int
+}
+
+// BadPassword reports accounts which are within Warning or Critical bad password attempts of lockout
+type BadPassword struct {
+	Warning  int
+	Critical int
+}
+
+// Stale reports accounts which did not log on for Warning or Critical days
+type Stale struct {
+	Warning         int
+	Critical        int
+	IncludeDisabled bool
+}
+
+//Name returns check name
+func (Disabled) Name() string { return "disabled" }
+
+//Name returns check name
+func (Locked) Name() string { return "locked" }
+
+//Name returns check name
+func (Expired) Name() string { return "expired" }
+
+//Name returns check name
+func (PasswordExpiry) Name() string { return "pwexpiry" }
+
+//Name returns check name
+func (BadPassword) Name() string { return "badpwd" }
+
+//Name returns check name
+func (Stale) Name() string { return "stale" }
+
+// requirer is implemented by checks which need data besides account attributes
+type requirer interface {
+	requires() fetchOptions
+}
+
+func (Locked) requires() fetchOptions         { return fetchOptions{policies: true} }
+func (PasswordExpiry) requires() fetchOptions { return fetchOptions{policies: true} }
+func (BadPassword) requires() fetchOptions    { return fetchOptions{policies: true, fanOut: true} }
+func (Stale) requires() fetchOptions          { return fetchOptions{fanOut: true} }
+
+// Report is outcome of checks evaluated against the same accounts, State is the worst state of all checks
+type Report struct {
+	State    State
+	Checks   []CheckResult
+	Accounts *AccountSet
+}
+
+//Evaluate runs checks against loaded accounts
+func Evaluate(set *AccountSet, checks ...Check) (*Report, error) {
+	report := &Report{State: StateOK, Accounts: set}
+
+	for _, check := range checks {
+		res, err := check.Evaluate(set)
+		if err != nil {
+			return nil, fmt.Errorf("%s check failed: %v", check.Name(), err)
+		}
+		report.State = Worse(report.State, res.State)
+		report.Checks = append(report.Checks, res)
+	}
+
+	return report, nil
+}
+
+//Evaluate reports disabled accounts, accounts in unknown state and accounts which were not found
+func (Disabled) Evaluate(set *AccountSet) (CheckResult, error) {
+	states := set.config.stateMap()
+	sum := newSummary("Disabled account(s)", "Account(s) in unknown state", "Account(s) not found")
+
+	for _, user := range set.Accounts {
+		switch user.Status {
+		case StatusOK:
+		case StatusDisabled:
+			sum.add("Disabled account(s)", states.disabled, user, "disabled")
+		case StatusUnknown:
+			sum.add("Account(s) in unknown state", states.unknown, user, fmt.Sprintf("unknown state (UAC:%s %s)", user.UACCode, user.UAC))
+		case StatusNotFound:
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+		}
+	}
+
+	sum.addExcluded(set, states)
+	return sum.result("disabled", "No disabled account(s)", nil), nil
+}
+
+//Evaluate reports accounts which expire within Warning or Critical days
+func (e Expired) Evaluate(set *AccountSet) (CheckResult, error) {
+	warning, critical := e.Warning, e.Critical
+	criticalLabel := fmt.Sprintf("Account(s) expiring within %d day(s)", critical)
+	warningLabel := fmt.Sprintf("Account(s) expiring within %d day(s)", warning)
+	expiredLabel := "Expired account(s)"
+	sum := newSummary(expiredLabel, criticalLabel, warningLabel, "Account(s) not found")
+	states := set.config.stateMap()
+	var expiring int
+	minDays := -1
+
+	for _, user := range set.Accounts {
+		if user.Status == StatusNotFound {
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+			continue
+		}
+
+		if accountNeverExpires(user.AccountExpires) {
+			continue
+		}
+
+		daysValid, err := getDaysFromNow(user.AccountExpires)
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("unable to parse accountExpires of %s: %v", user.Name, err)
+		}
+
+		if minDays == -1 || daysValid < minDays {
+			minDays = daysValid
+		}
+
+		if daysValid <= warning {
+			expiring++
+		}
+
+		if daysValid < 0 {
+			sum.add(expiredLabel, states.expired, user, fmt.Sprintf("account expired, DTE: %d", daysValid))
+		} else if daysValid > critical && daysValid <= warning {
+			sum.add(warningLabel, orThreshold(states.expiring, StateWarning), user, fmt.Sprintf("account expires, DTE: %d", daysValid))
+		} else if daysValid <= critical {
+			sum.add(criticalLabel, orThreshold(states.expiring, StateCritical), user, fmt.Sprintf("account expires, DTE: %d", daysValid))
+		}
+	}
+
+	perf := []string{perfCount("expiring", expiring)}
+	if minDays != -1 {
+		perf = append(perf, perfDays("min_dte", minDays, warning, critical))
+	}
+
+	sum.addExcluded(set, states)
+	return sum.result("expired", "No expiring account(s)", perf), nil
+}
+
+//Evaluate reports accounts which password expires within Warning or Critical days.
+//Accounts with DONT_EXPIRE_PASSWORD are skipped.
+func (e PasswordExpiry) Evaluate(set *AccountSet) (CheckResult, error) {
+	warning, critical := e.Warning, e.Critical
+	criticalLabel := fmt.Sprintf("Password(s) expiring within %d day(s)", critical)
+	warningLabel := fmt.Sprintf("Password(s) expiring within %d day(s)", warning)
+	expiredLabel := "Expired password(s)"
+	mustChangeLabel := "Password(s) must be changed at next logon"
+	sum := newSummary(expiredLabel, criticalLabel, warningLabel, mustChangeLabel, "Account(s) not found")
+	states := set.config.stateMap()
+	var expiring int
+	var changes int
+	minDays := -1
+
+	for _, user := range set.Accounts {
+		if user.Status == StatusNotFound {
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+			continue
+		}
+
+		if user.UAC.PasswordNeverExpires() {
+			set.config.logf("Password of %s never expires, skipping", user.Name)
+			continue
+		}
+
+		if user.PwdLastSet == "0" {
+			changes++
+			sum.add(mustChangeLabel, orThreshold(states.expiring, StateWarning), user, "password must be changed at next logon")
+			continue
+		}
+
+		expiry, never, err := passwordExpiry(user, set.policies.forUser(user))
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("unable to compute password expiry of %s: %v", user.Name, err)
+		}
+		if never {
+			continue
+		}
+
+		daysValid := daysFromNow(expiry)
+
+		if minDays == -1 || daysValid < minDays {
+			minDays = daysValid
+		}
+
+		if daysValid <= warning {
+			expiring++
+		}
+
+		if daysValid < 0 {
+			sum.add(expiredLabel, states.expired, user, fmt.Sprintf("password expired %s, DTE: %d", expiry.Format(timeFormat), daysValid))
+		} else if daysValid > critical && daysValid <= warning {
+			sum.add(warningLabel, orThreshold(states.expiring, StateWarning), user, fmt.Sprintf("password expires %s, DTE: %d", expiry.Format(timeFormat), daysValid))
+		} else if daysValid <= critical {
+			sum.add(criticalLabel, orThreshold(states.expiring, StateCritical), user, fmt.Sprintf("password expires %s, DTE: %d", expiry.Format(timeFormat), daysValid))
+		}
+	}
+
+	perf := []string{perfCount("pw_expiring", expiring), perfCount("pw_must_change", changes)}
+	if minDays != -1 {
+		perf = append(perf, perfDays("pw_min_dte", minDays, warning, critical))
+	}
+
+	sum.addExcluded(set, states)
+	return sum.result("pwexpiry", "No expiring password(s)", perf), nil
+}
+
+//Evaluate reports enabled accounts which did not log on for Warning or Critical days.
+//Accounts which never logged on are reported in separate section, aged by whenCreated.
+func (e Stale) Evaluate(set *AccountSet) (CheckResult, error) {
+	warning, critical := e.Warning, e.Critical
+	criticalLabel := fmt.Sprintf("Account(s) not used for %d day(s)", critical)
+	criticalNeverLabel := fmt.Sprintf("Account(s) never logged on, created %d day(s) ago", critical)
+	warningLabel := fmt.Sprintf("Account(s) not used for %d day(s)", warning)
+	warningNeverLabel := fmt.Sprintf("Account(s) never logged on, created %d day(s) ago", warning)
+	sum := newSummary(criticalLabel, criticalNeverLabel, warningLabel, warningNeverLabel, "Account(s) not found")
+	states := set.config.stateMap()
+	var stale int
+	var never int
+
+	for _, user := range set.Accounts {
+		if user.Status == StatusNotFound {
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+			continue
+		}
+
+		if user.UAC.Disabled() && !e.IncludeDisabled {
+			set.config.logf("Account %s is disabled, skipping", user.Name)
+			continue
+		}
+
+		lastLogon, loggedOn := user.LastLogonTime()
+		if !loggedOn {
+			created, err := time.Parse(generalizedTime, user.WhenCreated)
+			if err != nil {
+				return CheckResult{}, fmt.Errorf("unable to parse whenCreated of %s: %v", user.Name, err)
+			}
+			days := -daysFromNow(created)
+			reason := fmt.Sprintf("never logged on, created %d day(s) ago", days)
+
+			if days >= warning {
+				never++
+			}
+
+			if days >= critical {
+				sum.add(criticalNeverLabel, orThreshold(states.stale, StateCritical), user, reason)
+			} else if days >= warning {
+				sum.add(warningNeverLabel, orThreshold(states.stale, StateWarning), user, reason)
+			}
+			continue
+		}
+
+		days := -daysFromNow(lastLogon)
+		reason := fmt.Sprintf("last logon %d day(s) ago", days)
+
+		if days >= warning {
+			stale++
+		}
+
+		if days >= critical {
+			sum.add(criticalLabel, orThreshold(states.stale, StateCritical), user, reason)
+		} else if days >= warning {
+			sum.add(warningLabel, orThreshold(states.stale, StateWarning), user, reason)
+		}
+	}
+
+	perf := []string{perfCount("stale", stale), perfCount("never_logged_on", never)}
+
+	sum.addExcluded(set, states)
+	return sum.result("stale", "No stale account(s)", perf), nil
+}
+
+//Evaluate reports accounts which are within Warning or Critical bad password attempts of lockout.
+//Counts older than lockout observation window are ignored, as they are reset on next attempt.
+func (e BadPassword) Evaluate(set *AccountSet) (CheckResult, error) {
+	warning, critical := e.Warning, e.Critical
+	criticalLabel := fmt.Sprintf("Account(s) within %d attempt(s) of lockout", critical)
+	warningLabel := fmt.Sprintf("Account(s) within %d attempt(s) of lockout", warning)
+	sum := newSummary(criticalLabel, warningLabel, "Account(s) not found")
+	states := set.config.stateMap()
+	var nearLockout int
+
+	for _, user := range set.Accounts {
+		if user.Status == StatusNotFound {
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+			continue
+		}
+
+		policy := set.policies.forUser(user)
+		count, last, err := badPasswords(user, policy)
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("unable to parse bad password attributes of %s: %v", user.Name, err)
+		}
+		if count == 0 || policy.lockoutThreshold == 0 {
+			continue
+		}
+
+		remaining := policy.lockoutThreshold - count
+
+		reason := fmt.Sprintf("bad: %d/%d, last: %s", count, policy.lockoutThreshold, last.Format(timeFormat))
+		if user.BadPasswordDC != "" {
+			reason = reason + ", dc: " + user.BadPasswordDC
+		}
+
+		if remaining <= warning {
+			nearLockout++
+		}
+
+		if remaining <= critical {
+			sum.add(criticalLabel, orThreshold(states.nearLockout, StateCritical), user, reason)
+		} else if remaining <= warning {
+			sum.add(warningLabel, orThreshold(states.nearLockout, StateWarning), user, reason)
+		}
+	}
+
+	perf := []string{perfCount("near_lockout", nearLockout)}
+
+	sum.addExcluded(set, states)
+	return sum.result("badpwd", "No account(s) close to lockout", perf), nil
+}
+
+//Evaluate reports locked accounts, lockouts which already expired are ignored
+func (Locked) Evaluate(set *AccountSet) (CheckResult, error) {
+	sum := newSummary("Locked account(s)", "Account(s) not found")
+	states := set.config.stateMap()
+
+	for _, user := range set.Accounts {
+		if user.Status == StatusNotFound {
+			sum.add("Account(s) not found", states.notFound, user, "not found")
+			continue
+		}
+
+		locked, start, clears, err := lockoutState(user, set.policies.forUser(user))
+		if err != nil {
+			return CheckResult{}, fmt.Errorf("unable to compute lockout state of %s: %v", user.Name, err)
+		}
+		if !locked {
+			continue
+		}
+
+		until := "manual unlock"
+		if !clears.IsZero() {
+			until = clears.Format(timeFormat)
+		}
+		sum.add("Locked account(s)", states.locked, user, fmt.Sprintf("locked: %s, clears: %s", start.Format(timeFormat), until))
+	}
+
+	// locked count is reported by the account set for every check
+	sum.addExcluded(set, states)
+	return sum.result("locked", "No locked account(s)", nil), nil
+}
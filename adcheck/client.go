@@ -0,0 +1,114 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Client checks accounts over LDAP connection which is opened on first use and
+// re-established after any error. Client is not safe for concurrent use.
+type Client struct {
+	config Config
+	conn   *ldap.Conn
+}
+
+//New validates config and returns client, it does not connect yet
+func New(c Config) (*Client, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &Client{config: c}, nil
+}
+
+//Connect connects and binds, unless client is already connected
+func (cl *Client) Connect(ctx context.Context) error {
+	if cl.Connected() {
+		return nil
+	}
+	cl.Close()
+
+	conn, err := ldapClient(ctx, cl.config)
+	if err != nil {
+		return err
+	}
+	cl.conn = conn
+	return nil
+}
+
+//Connected reports if client holds open connection
+func (cl *Client) Connected() bool {
+	return cl.conn != nil && !cl.conn.IsClosing()
+}
+
+//Close closes connection, client reconnects on next use
+func (cl *Client) Close() error {
+	if cl.conn != nil {
+		cl.conn.Close()
+		cl.conn = nil
+	}
+	return nil
+}
+
+//Load collects selected accounts along with data needed by given checks
+func (cl *Client) Load(ctx context.Context, sel Selector, checks ...Check) (*AccountSet, error) {
+	if sel.Empty() {
+		return nil, errors.New("no accounts selected")
+	}
+
+	var opts fetchOptions
+	for _, check := range checks {
+		if r, ok := check.(requirer); ok {
+			need := r.requires()
+			opts.policies = opts.policies || need.policies
+			opts.fanOut = opts.fanOut || need.fanOut
+		}
+	}
+
+	if err := cl.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	set, err := loadAccounts(ctx, cl.conn, cl.config, sel, opts)
+	if err != nil {
+		cl.Close()
+		return nil, err
+	}
+	return set, nil
+}
+
+//Check loads selected accounts and evaluates checks against them
+func (cl *Client) Check(ctx context.Context, sel Selector, checks ...Check) (*Report, error) {
+	set, err := cl.Load(ctx, sel, checks...)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(set, checks...)
+}
+
+//CheckUsers evaluates checks against user accounts given by name
+func (cl *Client) CheckUsers(ctx context.Context, names []string, checks ...Check) (*Report, error) {
+	return cl.Check(ctx, Selector{Users: names}, checks...)
+}
+
+//CheckGroup evaluates checks against members of the group
+func (cl *Client) CheckGroup(ctx context.Context, group string, checks ...Check) (*Report, error) {
+	return cl.Check(ctx, Selector{Group: group}, checks...)
+}
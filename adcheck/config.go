@@ -0,0 +1,158 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+//Config struct to unmarshal yaml config to.
+type Config struct {
+	Host      string   `yaml:"host"`
+	Hosts     []string `yaml:"hosts"`
+	HostOrder string   `yaml:"hostOrder"`
+	SRV       struct {
+		Domain string `yaml:"domain"`
+		MSDCS  bool   `yaml:"msdcs"`
+	} `yaml:"srv"`
+	FanOut struct {
+		Enabled bool   `yaml:"enabled"`
+		Source  string `yaml:"source"`
+		Workers int    `yaml:"workers"`
+	} `yaml:"fanOut"`
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+	StartTLS           bool `yaml:"startTLS"`
+	LDAPS              bool `yaml:"ldaps"`
+	TLS                struct {
+		CAFile     string `yaml:"caFile"`
+		CertFile   string `yaml:"certFile"`
+		KeyFile    string `yaml:"keyFile"`
+		ServerName string `yaml:"serverName"`
+		MinVersion string `yaml:"minVersion"`
+	} `yaml:"tls"`
+	Timeout       int    `yaml:"timeout"`
+	BindMethod    string `yaml:"bindMethod"`
+	BindDN        string `yaml:"bindDN"`
+	BindPW        string `yaml:"bindPW"`
+	BindPWFile    string `yaml:"bindPWFile"`
+	BindPWEnv     string `yaml:"bindPWEnv"`
+	BindPWCommand string `yaml:"bindPWCommand"`
+	Kerberos      struct {
+		Keytab    string `yaml:"keytab"`
+		Principal string `yaml:"principal"`
+		Krb5Conf  string `yaml:"krb5Conf"`
+		SPN       string `yaml:"spn"`
+	} `yaml:"kerberos"`
+	UserSearch struct {
+		BaseDN   string `yaml:"baseDN"`
+		Filter   string `yaml:"filter"`
+		NameAttr string `yaml:"username"`
+	} `yaml:"userSearch"`
+	GroupSearch struct {
+		BaseDN      string `yaml:"baseDN"`
+		Filter      string `yaml:"filter"`
+		UserAttr    string `yaml:"userAttr"`
+		GroupAttr   string `yaml:"groupAttr"`
+		NameAttr    string `yaml:"nameAttr"`
+		ReadMembers bool   `yaml:"readMembers"`
+		Nested      bool   `yaml:"nested"`
+	} `yaml:"groupSearch"`
+	// States maps conditions to Nagios states, eg. disabled: OK, empty values keep defaults
+	States struct {
+		Disabled    string `yaml:"disabled"`
+		Locked      string `yaml:"locked"`
+		Expired     string `yaml:"expired"`
+		Expiring    string `yaml:"expiring"`
+		Stale       string `yaml:"stale"`
+		NearLockout string `yaml:"nearLockout"`
+		NotFound    string `yaml:"notFound"`
+		Unknown     string `yaml:"unknown"`
+		Excluded    string `yaml:"excluded"`
+	} `yaml:"states"`
+	// Logger receives verbose messages, nil disables them
+	Logger *log.Logger `yaml:"-" mapstructure:"-"`
+}
+
+//logf writes verbose message when Logger is set
+func (c Config) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf("--> "+format, args...)
+	}
+}
+
+//Validate config file
+func (c Config) Validate() error {
+
+	host := c.Host
+	bindDN := c.BindDN
+	passwordSources := 0
+	userSearchBaseDN := c.UserSearch.BaseDN
+	userSearchFilter := c.UserSearch.Filter
+	userSearchNameAttr := c.UserSearch.NameAttr
+	groupSearchBaseDN := c.GroupSearch.BaseDN
+	groupSearchFilter := c.GroupSearch.Filter
+	groupSearchUserAttr := c.GroupSearch.UserAttr
+	groupSearchNameAttr := c.GroupSearch.NameAttr
+
+	for _, source := range []string{c.BindPW, c.BindPWFile, c.BindPWEnv, c.BindPWCommand} {
+		if source != "" {
+			passwordSources++
+		}
+	}
+
+	// Fast checks. Perform these first for a more responsive CLI.
+	checks := []struct {
+		bad    bool
+		errMsg string
+	}{
+		{host == "" && len(c.Hosts) == 0 && c.SRV.Domain == "", "no ldap host specified!"},
+		{c.FanOut.Source != "" && c.FanOut.Source != "ntdsdsa" && c.FanOut.Source != "srv" && c.FanOut.Source != "config", "fanOut source must be ntdsdsa, srv or config!"},
+		{c.FanOut.Source == "srv" && c.SRV.Domain == "", "fanOut source srv requires srv domain!"},
+		{c.HostOrder != "" && c.HostOrder != "ordered" && c.HostOrder != "random", "hostOrder must be ordered or random!"},
+		{c.BindMethod != "" && c.BindMethod != "simple" && c.BindMethod != "gssapi", "bindMethod must be simple or gssapi!"},
+		{c.BindMethod != "gssapi" && bindDN == "", "bindDN not provided!"},
+		{c.BindMethod != "gssapi" && passwordSources == 0, "bindPW, bindPWFile, bindPWEnv or bindPWCommand not provided!"},
+		{passwordSources > 1, "only one of bindPW, bindPWFile, bindPWEnv or bindPWCommand can be provided!"},
+		{c.BindMethod == "gssapi" && c.Kerberos.Keytab == "", "kerberos keytab not provided!"},
+		{c.BindMethod == "gssapi" && c.Kerberos.Principal == "", "kerberos principal not provided!"},
+		{userSearchBaseDN == "", "userSearch baseDN value not provided!"},
+		{userSearchFilter == "", "userSearch filter value not provided!"},
+		{userSearchNameAttr == "", "userSearch nameAttr value not provided!"},
+		{groupSearchBaseDN == "", "groupSearch baseDN value not provided!"},
+		{groupSearchFilter == "", "groupSearch filter value not provided!"},
+		{groupSearchUserAttr == "", "groupSearch userAttr value not provided!"},
+		{groupSearchNameAttr == "", "groupSearch nameAttr value not provided!"},
+		{c.StartTLS && c.useLDAPS(), "startTLS can not be used with ldaps!"},
+		{(c.TLS.CertFile == "") != (c.TLS.KeyFile == ""), "tls certFile and keyFile must be provided together!"},
+		{c.TLS.MinVersion != "" && tlsVersions[c.TLS.MinVersion] == 0, "tls minVersion must be one of 1.0, 1.1, 1.2, 1.3!"},
+	}
+
+	var checkErrors []string
+
+	for _, check := range checks {
+		if check.bad {
+			checkErrors = append(checkErrors, check.errMsg)
+		}
+	}
+	checkErrors = append(checkErrors, c.stateErrors()...)
+	if len(checkErrors) != 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(checkErrors, " "))
+	}
+	return nil
+}
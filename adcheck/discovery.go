@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+//ldapHosts returns list of hosts to try, in the order they should be tried
+func ldapHosts(c Config) ([]string, error) {
+	var hosts []string
+
+	if c.Host != "" {
+		hosts = append(hosts, c.Host)
+	}
+	hosts = append(hosts, c.Hosts...)
+
+	if c.HostOrder == "random" {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		r.Shuffle(len(hosts), func(i, j int) { hosts[i], hosts[j] = hosts[j], hosts[i] })
+	}
+
+	if c.SRV.Domain != "" {
+		discovered, err := discoverHosts(c)
+		if err != nil {
+			if len(hosts) == 0 {
+				return nil, err
+			}
+			c.logf("%v", err)
+		}
+		hosts = append(hosts, discovered...)
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no ldap host available")
+	}
+
+	return hosts, nil
+}
+
+//discoverHosts looks up domain controllers in DNS SRV records.
+//Records are returned sorted by priority and randomized by weight.
+func discoverHosts(c Config) ([]string, error) {
+	var hosts []string
+	name := c.SRV.Domain
+
+	if c.SRV.MSDCS {
+		name = "dc._msdcs." + name
+	}
+
+	c.logf("Looking up _ldap._tcp.%s SRV records", name)
+
+	_, records, err := net.LookupSRV("ldap", "tcp", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover domain controllers: %v", err)
+	}
+
+	for _, srv := range records {
+		port := strconv.Itoa(int(srv.Port))
+		if c.useLDAPS() {
+			port = ldap.DefaultLdapsPort
+		}
+		host := net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), port)
+		c.logf("Discovered %s (priority: %d, weight: %d)", host, srv.Priority, srv.Weight)
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package adcheck checks state of Active Directory accounts: disabled, locked, expiring
+// accounts and passwords, bad password attempts and stale accounts.
+//
+//	client, err := adcheck.New(config)
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	report, err := client.CheckGroup(ctx, "APP-ADMINS", adcheck.Disabled{}, adcheck.Locked{})
+//	if err != nil {
+//		return err
+//	}
+//	for _, res := range report.Checks {
+//		for _, account := range res.Accounts {
+//			fmt.Println(res.Name, account.State, account.Account.DN, account.Reason)
+//		}
+//	}
+package adcheck
@@ -0,0 +1,219 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+//defaultWorkers is number of domain controllers queried concurrently in fan-out mode
+const defaultWorkers = 4
+
+//fanOut queries every domain controller for non-replicated attributes of given accounts and merges
+//them into results: highest badPwdCount, latest badPasswordTime (along with DC which recorded it) and latest lastLogon.
+func fanOut(ctx context.Context, conn *ldap.Conn, c Config, r []Account) ([]Account, error) {
+	var dns []string
+	var mu sync.Mutex
+	var failed []string
+	var answered int
+
+	dcs, err := domainControllers(ctx, conn, c)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range r {
+		if user.Status != StatusNotFound {
+			dns = append(dns, user.DN)
+		}
+	}
+
+	workers := c.FanOut.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	c.logf("Querying %d domain controllers with %d workers", len(dcs), workers)
+
+	// bound DC is queried again along with the others, so values are merged from DC answers only
+	// and DC which recorded the latest bad password is known even when it is the bound one
+	merged := map[string]*Account{}
+	for i := range r {
+		r[i].BadPwdCount = ""
+		r[i].BadPasswordTime = ""
+		r[i].BadPasswordDC = ""
+		merged[strings.ToLower(r[i].DN)] = &r[i]
+	}
+
+	queue := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dc := range queue {
+				res, err := queryDC(ctx, c, dc, dns)
+
+				mu.Lock()
+				if err != nil {
+					c.logf("%s: %v", dc, err)
+					failed = append(failed, dc)
+				} else {
+					answered++
+					for _, user := range res {
+						if target, ok := merged[strings.ToLower(user.DN)]; ok {
+							mergeResult(target, user, dc)
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, dc := range dcs {
+		queue <- dc
+	}
+	close(queue)
+	wg.Wait()
+
+	if answered == 0 {
+		return nil, fmt.Errorf("no domain controller answered: %s", strings.Join(failed, ", "))
+	}
+
+	return r, nil
+}
+
+//queryDC binds to single domain controller and resolves accounts on it
+func queryDC(ctx context.Context, c Config, dc string, dns []string) ([]Account, error) {
+	conn, err := ldapDial(ctx, c, dc)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ldapBind(ctx, conn, c, dc); err != nil {
+		return nil, err
+	}
+
+	return ldapCheckDNs(ctx, conn, c, dns)
+}
+
+//mergeResult merges non-replicated attributes read from dc into target
+func mergeResult(target *Account, user Account, dc string) {
+	if fileTimeValue(user.BadPasswordTime) > fileTimeValue(target.BadPasswordTime) {
+		target.BadPasswordTime = user.BadPasswordTime
+		target.BadPasswordDC = dc
+	}
+	if count, _ := strconv.Atoi(user.BadPwdCount); count > 0 {
+		if current, _ := strconv.Atoi(target.BadPwdCount); count > current {
+			target.BadPwdCount = user.BadPwdCount
+		}
+	}
+	if fileTimeValue(user.LastLogon) > fileTimeValue(target.LastLogon) {
+		target.LastLogon = user.LastLogon
+	}
+}
+
+//fileTimeValue parses FILETIME attribute, missing or invalid values are treated as zero
+func fileTimeValue(value string) int64 {
+	v, _ := strconv.ParseInt(value, 10, 64)
+	return v
+}
+
+//domainControllers enumerates domain controllers to query in fan-out mode.
+//Source is nTDSDSA objects (default), DNS SRV records or configured hosts.
+func domainControllers(ctx context.Context, conn *ldap.Conn, c Config) ([]string, error) {
+	switch c.FanOut.Source {
+	case "config":
+		var hosts []string
+		if c.Host != "" {
+			hosts = append(hosts, c.Host)
+		}
+		return append(hosts, c.Hosts...), nil
+	case "srv":
+		return discoverHosts(c)
+	default:
+		return ntdsDomainControllers(ctx, conn)
+	}
+}
+
+//ntdsDomainControllers returns dNSHostName of every server object holding nTDSDSA (NTDS Settings) object
+//of a DC which hosts the domain
+func ntdsDomainControllers(ctx context.Context, conn *ldap.Conn) ([]string, error) {
+	var dcs []string
+
+	rootDSE, err := readRootDSE(ctx, conn, "configurationNamingContext", "defaultNamingContext")
+	if err != nil {
+		return nil, err
+	}
+	configDN := rootDSE.GetAttributeValue("configurationNamingContext")
+	domainDN := rootDSE.GetAttributeValue("defaultNamingContext")
+
+	// Sites hold DCs of the whole forest, only DCs hosting this domain have the accounts
+	searchRequest := ldap.NewSearchRequest(
+		"CN=Sites,"+configDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&(objectClass=nTDSDSA)(msDS-hasDomainNCs=%s))", ldap.EscapeFilter(domainDN)),
+		[]string{"dn"},
+		nil,
+	)
+
+	sr, err := searchPaged(ctx, conn, searchRequest, "dc search")
+	if err != nil {
+		return nil, err
+	}
+
+	servers := map[string]bool{}
+	for _, entry := range sr.Entries {
+		if i := strings.Index(entry.DN, ","); i >= 0 {
+			servers[strings.ToLower(entry.DN[i+1:])] = true
+		}
+	}
+
+	searchRequest = ldap.NewSearchRequest(
+		"CN=Sites,"+configDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+		"(&(objectClass=server)(dNSHostName=*))",
+		[]string{"dNSHostName"},
+		nil,
+	)
+
+	sr, err = searchPaged(ctx, conn, searchRequest, "dc search")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range sr.Entries {
+		if servers[strings.ToLower(entry.DN)] {
+			dcs = append(dcs, entry.GetAttributeValue("dNSHostName"))
+		}
+	}
+
+	if len(dcs) == 0 {
+		return nil, fmt.Errorf("no domain controllers of %s found in %s", domainDN, configDN)
+	}
+
+	return dcs, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeResult(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	older := fileTime(now.Add(-time.Hour))
+	latest := fileTime(now)
+
+	type answer struct {
+		dc   string
+		user Account
+	}
+
+	tests := []struct {
+		name    string
+		answers []answer
+		count   string
+		time    string
+		dc      string
+		logon   string
+	}{
+		{
+			"bound dc holds latest attempt",
+			[]answer{
+				{"dc1", Account{BadPwdCount: "2", BadPasswordTime: latest, LastLogon: older}},
+				{"dc2", Account{BadPwdCount: "1", BadPasswordTime: older, LastLogon: latest}},
+			},
+			"2", latest, "dc1", latest,
+		},
+		{
+			"later dc holds latest attempt",
+			[]answer{
+				{"dc1", Account{BadPwdCount: "1", BadPasswordTime: older}},
+				{"dc2", Account{BadPwdCount: "3", BadPasswordTime: latest}},
+			},
+			"3", latest, "dc2", "",
+		},
+		{
+			"highest count is kept",
+			[]answer{
+				{"dc1", Account{BadPwdCount: "4", BadPasswordTime: older}},
+				{"dc2", Account{BadPwdCount: "1", BadPasswordTime: latest}},
+			},
+			"4", latest, "dc2", "",
+		},
+		{
+			"no bad password",
+			[]answer{
+				{"dc1", Account{BadPwdCount: "0", LastLogon: older}},
+				{"dc2", Account{BadPwdCount: "0"}},
+			},
+			"", "", "", older,
+		},
+	}
+
+	for _, tt := range tests {
+		var target Account
+		for _, a := range tt.answers {
+			mergeResult(&target, a.user, a.dc)
+		}
+		if target.BadPwdCount != tt.count || target.BadPasswordTime != tt.time || target.BadPasswordDC != tt.dc || target.LastLogon != tt.logon {
+			t.Errorf("%s: count %q, time %q, dc %q, lastLogon %q", tt.name, target.BadPwdCount, target.BadPasswordTime, target.BadPasswordDC, target.LastLogon)
+		}
+		if target.LastLogonTimestamp != "" {
+			t.Errorf("%s: lastLogonTimestamp changed to %q", tt.name, target.LastLogonTimestamp)
+		}
+	}
+}
+
+func TestLastLogonTime(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		user  Account
+		last  time.Time
+		never bool
+	}{
+		{"never", Account{LastLogonTimestamp: "0"}, time.Time{}, true},
+		{"timestamp only", Account{LastLogonTimestamp: fileTime(now)}, now, false},
+		{"lastLogon only", Account{LastLogon: fileTime(now)}, now, false},
+		{"lastLogon later", Account{LastLogonTimestamp: fileTime(now.Add(-240 * time.Hour)), LastLogon: fileTime(now)}, now, false},
+		{"timestamp later", Account{LastLogonTimestamp: fileTime(now), LastLogon: fileTime(now.Add(-time.Hour))}, now, false},
+	}
+
+	for _, tt := range tests {
+		last, ok := tt.user.LastLogonTime()
+		if ok == tt.never || !last.Equal(tt.last) {
+			t.Errorf("%s: %s, %v", tt.name, last, ok)
+		}
+	}
+}
+
+func TestStaleUsesLastLogon(t *testing.T) {
+	now := time.Now()
+	set := &AccountSet{Accounts: []Account{
+		{Name: "recent", UACCode: "512", LastLogonTimestamp: fileTime(now.Add(-100 * 24 * time.Hour)), LastLogon: fileTime(now.Add(-time.Hour))},
+		{Name: "stale", UACCode: "512", LastLogonTimestamp: fileTime(now.Add(-100 * 24 * time.Hour))},
+	}}
+
+	res, err := Stale{Warning: 30, Critical: 90}.Evaluate(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Accounts) != 1 || res.Accounts[0].Account.Name != "stale" || res.State != StateCritical {
+		t.Errorf("unexpected result: %s %+v", res.State, res.Accounts)
+	}
+}
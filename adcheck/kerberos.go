@@ -0,0 +1,118 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+	"github.com/jcmturner/gokrb5/v8/client"
+)
+
+//defaultKrb5Conf is used when kerberos krb5Conf is not configured
+const defaultKrb5Conf = "/etc/krb5.conf"
+
+//ldapBind authenticates connection with configured bind method
+func ldapBind(ctx context.Context, conn *ldap.Conn, c Config, host string) error {
+	if c.BindMethod == "gssapi" {
+		return kerberosBind(ctx, conn, c, host)
+	}
+
+	password, err := bindPassword(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	if err := setTimeout(ctx, conn, "bind"); err != nil {
+		return err
+	}
+
+	if err := conn.Bind(c.BindDN, password); err != nil {
+		return opError(ctx, "bind", err)
+	}
+
+	return nil
+}
+
+//kerberosBind performs SASL GSSAPI bind with credentials from keytab. KDC exchanges use gokrb5
+//timeouts and ignore ctx, so bind runs in background and connection is closed when ctx is done.
+func kerberosBind(ctx context.Context, conn *ldap.Conn, c Config, host string) error {
+	if err := setTimeout(ctx, conn, "bind"); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- gssapiBind(ctx, conn, c, host)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		conn.Close()
+		return opError(ctx, "bind", ctx.Err())
+	}
+}
+
+//gssapiBind logs in to KDC with keytab and binds with service ticket of the LDAP server
+func gssapiBind(ctx context.Context, conn *ldap.Conn, c Config, host string) error {
+	krb5Conf := c.Kerberos.Krb5Conf
+	if krb5Conf == "" {
+		krb5Conf = defaultKrb5Conf
+	}
+
+	username, realm := splitPrincipal(c.Kerberos.Principal)
+
+	spn := c.Kerberos.SPN
+	if spn == "" {
+		spn = "ldap/" + serverName(host)
+	}
+
+	c.logf("Binding as %s to %s using keytab %s", c.Kerberos.Principal, spn, c.Kerberos.Keytab)
+
+	krb, err := gssapi.NewClientWithKeytab(username, realm, c.Kerberos.Keytab, krb5Conf, client.DisablePAFXFAST(true))
+	if err != nil {
+		return fmt.Errorf("kerberos: %v", err)
+	}
+	defer krb.Close()
+
+	if err := krb.Login(); err != nil {
+		return fmt.Errorf("kerberos login failed: %v", err)
+	}
+
+	if err := setTimeout(ctx, conn, "bind"); err != nil {
+		return err
+	}
+
+	if err := conn.GSSAPIBind(krb, spn, ""); err != nil {
+		return opError(ctx, "bind", err)
+	}
+
+	return nil
+}
+
+//splitPrincipal splits user@REALM principal, realm is empty when principal has none
+func splitPrincipal(principal string) (string, string) {
+	if i := strings.LastIndex(principal, "@"); i >= 0 {
+		return principal[:i], principal[i+1:]
+	}
+	return principal, ""
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+//silentKDC accepts KDC connections and never answers, like KDC which hangs
+func silentKDC(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		l.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+//kerberosConfig writes keytab and krb5.conf pointing to given KDC
+func kerberosConfig(t *testing.T, kdc string) Config {
+	dir, err := ioutil.TempDir("", "checkad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	kt := keytab.New()
+	if err := kt.AddEntry("monitor", "EXAMPLE.COM", "secret", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatal(err)
+	}
+	ktData, err := kt.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	krb5Conf := fmt.Sprintf(`[libdefaults]
+ default_realm = EXAMPLE.COM
+ dns_lookup_kdc = false
+ udp_preference_limit = 1
+
+[realms]
+ EXAMPLE.COM = {
+  kdc = %s
+ }
+`, kdc)
+
+	var c Config
+	c.BindMethod = "gssapi"
+	c.Kerberos.Principal = "monitor@EXAMPLE.COM"
+	c.Kerberos.Keytab = filepath.Join(dir, "monitor.keytab")
+	c.Kerberos.Krb5Conf = filepath.Join(dir, "krb5.conf")
+	c.Kerberos.SPN = "ldap/dc1.example.com"
+
+	if err := ioutil.WriteFile(c.Kerberos.Keytab, ktData, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(c.Kerberos.Krb5Conf, []byte(krb5Conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestKerberosBindTimeout(t *testing.T) {
+	c := kerberosConfig(t, silentKDC(t))
+
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := ldap.NewConn(client, false)
+	conn.Start()
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := ldapBind(ctx, conn, c, "dc1.example.com")
+	if err == nil || err.Error() != "timed out during bind" {
+		t.Fatalf("expected bind timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("bind returned after %s, expected it to stop at deadline", elapsed)
+	}
+}
+
+func TestSplitPrincipal(t *testing.T) {
+	tests := []struct {
+		principal string
+		username  string
+		realm     string
+	}{
+		{"monitor@EXAMPLE.COM", "monitor", "EXAMPLE.COM"},
+		{"monitor", "monitor", ""},
+		{"svc/host@EXAMPLE.COM", "svc/host", "EXAMPLE.COM"},
+	}
+
+	for _, tt := range tests {
+		username, realm := splitPrincipal(tt.principal)
+		if username != tt.username || realm != tt.realm {
+			t.Errorf("splitPrincipal(%q) = %q, %q, want %q, %q", tt.principal, username, realm, tt.username, tt.realm)
+		}
+	}
+}
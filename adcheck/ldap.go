@@ -0,0 +1,580 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+const (
+	NeverDerefAliases   = 0
+	DerefInSearching    = 1
+	DerefFindingBaseObj = 2
+	DerefAlways         = 3
+)
+
+// Status is account state derived from userAccountControl
+type Status int
+
+// Account statuses, values match Nagios exit codes used by earlier versions
+const (
+	StatusOK       Status = 0
+	StatusDisabled Status = 2
+	StatusUnknown  Status = 3
+	StatusNotFound Status = 5
+)
+
+// Account stores user account status along with raw values of AD attributes needed by checks.
+// Accounts which were not found have only Name (and DN when searched by DN) set.
+type Account struct {
+	DN                     string
+	Name                   string
+	UPN                    string
+	UACCode                string
+	UAC                    UAC
+	UACComputed            string
+	BadPwdCount            string
+	BadPasswordTime        string
+	BadPasswordDC          string
+	LastLogon              string
+	AccountExpires         string
+	LockoutTime            string
+	PwdLastSet             string
+	PasswordExpiryComputed string
+	ResultantPSO           string
+	LastLogonTimestamp     string
+	WhenCreated            string
+	Status                 Status
+}
+
+//LastLogonTime returns the later of lastLogonTimestamp and lastLogon, ok is false if account never logged on.
+//lastLogonTimestamp replicates with up to 14 days delay, lastLogon is exact but kept per DC.
+func (a Account) LastLogonTime() (time.Time, bool) {
+	last, ok := FileTime(a.LastLogonTimestamp)
+	if t, dcOK := FileTime(a.LastLogon); dcOK && (!ok || t.After(last)) {
+		return t, true
+	}
+	return last, ok
+}
+
+//ldapClient connects to the first available host, binds and returns connection
+func ldapClient(ctx context.Context, c Config) (*ldap.Conn, error) {
+	hosts, err := ldapHosts(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var client *ldap.Conn
+	var connected string
+	for _, host := range hosts {
+		client, err = ldapDial(ctx, c, host)
+		if err == nil {
+			c.logf("Connected to %s", host)
+			connected = host
+			break
+		}
+		c.logf("Unable to connect to %s: %v", host, err)
+		if deadlineExceeded(ctx) {
+			return nil, err
+		}
+	}
+	if client == nil {
+		return nil, fmt.Errorf("unable to connect: %v", err)
+	}
+
+	if err := ldapBind(ctx, client, c, connected); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+//ldapDial opens connection to single host, upgrading it with StartTLS if configured.
+//Dial and TLS handshakes are bounded by context deadline.
+func ldapDial(ctx context.Context, c Config, host string) (*ldap.Conn, error) {
+	u, err := url.Parse(c.ldapURL(host))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %s: %v", host, err)
+	}
+
+	tc, err := tlsConfig(c, host)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %v", err)
+	}
+
+	c.logf("Connecting to %s", u)
+
+	deadline, _ := ctx.Deadline()
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.DialContext(ctx, "tcp", hostPort(u.Host, ldap.DefaultLdapPort))
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u.Host, ldap.DefaultLdapsPort), tc)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, opError(ctx, "dial", err)
+	}
+
+	client := ldap.NewConn(conn, u.Scheme == "ldaps")
+	client.Start()
+
+	if c.StartTLS {
+		c.logf("Starting TLS")
+
+		if err := setTimeout(ctx, client, "starttls"); err != nil {
+			client.Close()
+			return nil, err
+		}
+
+		conn.SetDeadline(deadline)
+		err = client.StartTLS(tc)
+		if err != nil {
+			client.Close()
+			return nil, opError(ctx, "starttls", err)
+		}
+		conn.SetDeadline(time.Time{})
+	}
+
+	return client, nil
+}
+
+//hostPort appends default port to host if it has none
+func hostPort(host string, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+//setTimeout limits next LDAP operation to the time left in context
+func setTimeout(ctx context.Context, conn *ldap.Conn, phase string) error {
+	if deadlineExceeded(ctx) {
+		return fmt.Errorf("timed out during %s", phase)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetTimeout(time.Until(deadline))
+	}
+	return nil
+}
+
+//opError describes failed LDAP operation, reporting timeout if context deadline passed
+func opError(ctx context.Context, phase string, err error) error {
+	if deadlineExceeded(ctx) {
+		return fmt.Errorf("timed out during %s", phase)
+	}
+	return fmt.Errorf("%s failed: %v", phase, err)
+}
+
+//deadlineExceeded reports if context is done or its deadline has passed
+func deadlineExceeded(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
+
+//batchSize is number of accounts resolved with a single search filter
+const batchSize = 50
+
+//userAttributes returns attributes needed to evaluate account state
+func userAttributes(c Config) []string {
+	return []string{"dn", c.UserSearch.NameAttr, "userPrincipalName", "displayName", "userAccountControl", "accountExpires", "lockoutTime",
+		"pwdLastSet", "msDS-UserPasswordExpiryTimeComputed", "msDS-ResultantPSO", "lastLogonTimestamp", "whenCreated",
+		"msDS-User-Account-Control-Computed", "badPwdCount", "badPasswordTime", "lastLogon"}
+}
+
+//ldapCheckUser searches for user and returns account attributes
+func ldapCheckUser(ctx context.Context, conn *ldap.Conn, c Config, searchByAttr string, userName string) ([]Account, error) {
+
+	var res = []Account{}
+	var searchFilter string
+
+	c.logf("Checking if [%s] user account is disabled...", userName)
+
+	if searchByAttr == "CN" || searchByAttr == "cn" {
+		searchFilter = fmt.Sprintf("(&%s(%s))", c.UserSearch.Filter, userName)
+
+	} else {
+		searchFilter = fmt.Sprintf("(&%s(%s=%s))", c.UserSearch.Filter, searchByAttr, userName)
+
+	}
+
+	c.logf("Using search filter: %s", searchFilter)
+
+	searchRequest := ldap.NewSearchRequest(
+		c.UserSearch.BaseDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+		searchFilter,
+		userAttributes(c),
+		nil,
+	)
+
+	sr, err := searchPaged(ctx, conn, searchRequest, "user search")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sr.Entries) == 0 {
+		var user = Account{}
+		user.Name = userName
+		user.Status = StatusNotFound
+		res = append(res, user)
+	} else {
+
+		for _, entry := range sr.Entries {
+			res = append(res, userResult(c, entry))
+		}
+
+	}
+
+	return res, nil
+}
+
+//ldapCheckUsers resolves users in batches of (|(nameAttr=a)(nameAttr=b)...) filters.
+//Names containing wildcards are searched one by one, as they may match several accounts.
+func ldapCheckUsers(ctx context.Context, conn *ldap.Conn, c Config, userNames []string) ([]Account, error) {
+	var res = []Account{}
+	var batch []string
+	nameAttr := c.UserSearch.NameAttr
+
+	for _, userName := range userNames {
+		if strings.Contains(userName, "*") || strings.EqualFold(nameAttr, "cn") {
+			r, err := ldapCheckUser(ctx, conn, c, nameAttr, userName)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, r...)
+		} else {
+			batch = append(batch, userName)
+		}
+	}
+
+	for _, names := range batches(batch, batchSize) {
+		searchFilter := userBatchFilter(c, names)
+
+		c.logf("Using search filter: %s", searchFilter)
+
+		searchRequest := ldap.NewSearchRequest(
+			c.UserSearch.BaseDN,
+			ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+			searchFilter,
+			userAttributes(c),
+			nil,
+		)
+
+		sr, err := searchPaged(ctx, conn, searchRequest, "user search")
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, matchUsers(c, names, sr.Entries)...)
+	}
+
+	return res, nil
+}
+
+//batches splits items into batches of at most size items
+func batches(items []string, size int) [][]string {
+	var res [][]string
+	for len(items) > 0 {
+		n := size
+		if len(items) < n {
+			n = len(items)
+		}
+		res = append(res, items[:n])
+		items = items[n:]
+	}
+	return res
+}
+
+//userBatchFilter returns user search filter matching any of given names
+func userBatchFilter(c Config, names []string) string {
+	var terms string
+	for _, userName := range names {
+		terms += fmt.Sprintf("(%s=%s)", c.UserSearch.NameAttr, ldap.EscapeFilter(userName))
+	}
+	return fmt.Sprintf("(&%s(|%s))", c.UserSearch.Filter, terms)
+}
+
+//matchUsers pairs searched names with entries in order of names, names without entry are reported as not found
+func matchUsers(c Config, names []string, entries []*ldap.Entry) []Account {
+	var res []Account
+	for _, userName := range names {
+		found := false
+		for _, entry := range entries {
+			if strings.EqualFold(entry.GetAttributeValue(c.UserSearch.NameAttr), userName) {
+				res = append(res, userResult(c, entry))
+				found = true
+			}
+		}
+		if !found {
+			res = append(res, Account{Name: userName, Status: StatusNotFound})
+		}
+	}
+	return res
+}
+
+//ldapCheckDNs resolves accounts by distinguished name in batches. DNs which do not match user search filter,
+//eg. contacts or foreign security principals, are skipped.
+func ldapCheckDNs(ctx context.Context, conn *ldap.Conn, c Config, dns []string) ([]Account, error) {
+	var res = []Account{}
+
+	for _, batch := range batches(dns, batchSize) {
+		var terms string
+		for _, dn := range batch {
+			terms += fmt.Sprintf("(distinguishedName=%s)", ldap.EscapeFilter(dn))
+		}
+
+		searchRequest := ldap.NewSearchRequest(
+			c.UserSearch.BaseDN,
+			ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf("(&%s(objectClass=user)(|%s))", c.UserSearch.Filter, terms),
+			userAttributes(c),
+			nil,
+		)
+
+		sr, err := searchPaged(ctx, conn, searchRequest, "user search")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dn := range batch {
+			found := false
+			for _, entry := range sr.Entries {
+				if strings.EqualFold(entry.DN, dn) {
+					res = append(res, userResult(c, entry))
+					found = true
+				}
+			}
+			if !found {
+				c.logf("Skipping member which is not a user: %s", dn)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+//userResult converts search entry to Account and classifies account state
+func userResult(c Config, entry *ldap.Entry) Account {
+	var user = Account{}
+	retCode := StatusOK
+
+	user.DN = entry.DN
+	user.Name = entry.GetAttributeValue(c.UserSearch.NameAttr)
+	user.UPN = entry.GetAttributeValue("userPrincipalName")
+	user.UACCode = entry.GetAttributeValue("userAccountControl")
+	user.AccountExpires = entry.GetAttributeValue("accountExpires")
+	user.LockoutTime = entry.GetAttributeValue("lockoutTime")
+	user.PwdLastSet = entry.GetAttributeValue("pwdLastSet")
+	user.PasswordExpiryComputed = entry.GetAttributeValue("msDS-UserPasswordExpiryTimeComputed")
+	user.ResultantPSO = entry.GetAttributeValue("msDS-ResultantPSO")
+	user.LastLogonTimestamp = entry.GetAttributeValue("lastLogonTimestamp")
+	user.UACComputed = entry.GetAttributeValue("msDS-User-Account-Control-Computed")
+	user.BadPwdCount = entry.GetAttributeValue("badPwdCount")
+	user.BadPasswordTime = entry.GetAttributeValue("badPasswordTime")
+	user.LastLogon = entry.GetAttributeValue("lastLogon")
+	user.WhenCreated = entry.GetAttributeValue("whenCreated")
+
+	uac, err := parseUAC(user.UACCode)
+	user.UAC = uac
+
+	c.logf("Found user: [%s]", entry.GetAttributeValue("displayName"))
+	for _, attr := range entry.Attributes {
+		c.logf("  %s: %s", attr.Name, strings.Join(attr.Values, ", "))
+	}
+	c.logf("UAC flags: %s", uac)
+
+	if err != nil {
+		retCode = StatusUnknown
+	} else if uac.Disabled() {
+		retCode = StatusDisabled
+	} else if !uac.NormalAccount() {
+		retCode = StatusUnknown
+	} else {
+		retCode = StatusOK
+	}
+	user.Status = retCode
+
+	return user
+}
+
+//ldapCheckGroup checks all members of the group, member search returns account attributes directly.
+func ldapCheckGroup(ctx context.Context, conn *ldap.Conn, c Config, groupName string) ([]Account, error) {
+
+	var groupDN string
+
+	c.logf("Checking if %s members accounts are disabled...", groupName)
+
+	groupDN, err := getGroupDN(ctx, conn, c, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	if groupDN == "" {
+		return nil, fmt.Errorf("group %s not found", groupName)
+	}
+
+	c.logf("Found group: %s", groupDN)
+
+	members, err := groupMembers(ctx, conn, c, groupDN)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logf("Found %d users...", len(members))
+
+	return members, nil
+}
+
+//ldapCheckOU checks all user accounts in the OU subtree
+func ldapCheckOU(ctx context.Context, conn *ldap.Conn, c Config, ouDN string) ([]Account, error) {
+	var members []Account
+
+	filter := fmt.Sprintf("(&%s(objectClass=user))", c.UserSearch.Filter)
+
+	c.logf("Checking accounts in %s, using search filter: %s", ouDN, filter)
+
+	searchRequest := ldap.NewSearchRequest(
+		ouDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false, filter, userAttributes(c), nil,
+	)
+
+	sr, err := searchPaged(ctx, conn, searchRequest, "ou search")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range sr.Entries {
+		members = append(members, userResult(c, entry))
+	}
+
+	c.logf("Found %d users...", len(members))
+
+	return members, nil
+}
+
+//excludeResults removes accounts which DN contains excluded OU, returns removed accounts along with the rest
+func excludeResults(c Config, members []Account, exclude string) ([]Account, []Account) {
+	var res = []Account{}
+	var excluded []Account
+
+	for _, member := range members {
+		c.logf("%s", member.DN)
+		if exclude != "" {
+			if strings.Contains(member.DN, exclude) {
+				excluded = append(excluded, member)
+			} else {
+				res = append(res, member)
+			}
+		} else {
+			res = append(res, member)
+		}
+	}
+
+	for _, entry := range excluded {
+		c.logf("Excluded user: %s", entry.DN)
+	}
+
+	return res, excluded
+}
+
+//getGroupDD returns full DN of group
+func getGroupDN(ctx context.Context, conn *ldap.Conn, c Config, groupName string) (string, error) {
+	var groupDN string
+
+	searchGroupDN := ldap.NewSearchRequest(
+		c.UserSearch.BaseDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&%s(%s=%s))", c.GroupSearch.Filter, c.GroupSearch.NameAttr, groupName),
+		[]string{"dn"},
+		nil,
+	)
+
+	sgDN, err := searchPaged(ctx, conn, searchGroupDN, "group search")
+	if err != nil {
+		return "", err
+	}
+
+	if len(sgDN.Entries) == 1 {
+		groupDN = strings.Replace(sgDN.Entries[0].DN, "\\", "", -1)
+	}
+
+	return groupDN, nil
+}
+
+func getDaysFromNow(accExp string) (int, error) {
+	ae, err := strconv.ParseInt(accExp, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return daysFromNow(fileTimeToTime(ae)), nil
+}
+
+//accountNeverExpires reports if accountExpires value means account does not expire
+func accountNeverExpires(accExp string) bool {
+	return accExp == "0" || accExp == "9223372036854775807"
+}
+
+//FileTime converts FILETIME attribute value to time, ok is false when value is unset,
+//invalid or means never (0 or max int64)
+func FileTime(value string) (t time.Time, ok bool) {
+	v := fileTimeValue(value)
+	if v <= 0 || v == 9223372036854775807 {
+		return t, false
+	}
+	return fileTimeToTime(v), true
+}
+
+//daysFromNow returns number of whole days between now and t, negative for past
+func daysFromNow(t time.Time) int {
+	return int((t.Unix() - time.Now().Unix()) / 86400)
+}
+
+//timeFormat is layout of timestamps printed in plugin output
+const timeFormat = "2006-01-02 15:04:05"
+
+//generalizedTime is layout of AD GeneralizedTime attributes, eg. whenCreated
+const generalizedTime = "20060102150405.0Z"
+
+//fileTimeToTime converts Windows FILETIME (100ns intervals since 1601-01-01 UTC) to time
+func fileTimeToTime(ft int64) time.Time {
+	return time.Unix((ft/10000000)-11644473600, 0)
+}
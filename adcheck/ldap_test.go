@@ -0,0 +1,105 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestBatches(t *testing.T) {
+	names := func(n int) []string {
+		var res []string
+		for i := 0; i < n; i++ {
+			res = append(res, fmt.Sprintf("user%d", i))
+		}
+		return res
+	}
+
+	tests := []struct {
+		items int
+		sizes []int
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{batchSize, []int{batchSize}},
+		{batchSize + 1, []int{batchSize, 1}},
+		{2*batchSize + 7, []int{batchSize, batchSize, 7}},
+	}
+
+	for _, tt := range tests {
+		items := names(tt.items)
+		var sizes []int
+		var joined []string
+		for _, batch := range batches(items, batchSize) {
+			sizes = append(sizes, len(batch))
+			joined = append(joined, batch...)
+		}
+		if !reflect.DeepEqual(sizes, tt.sizes) {
+			t.Errorf("%d items: batch sizes %v, want %v", tt.items, sizes, tt.sizes)
+		}
+		if !reflect.DeepEqual(joined, items) {
+			t.Errorf("%d items: batches do not keep order of items", tt.items)
+		}
+	}
+}
+
+func TestUserBatchFilter(t *testing.T) {
+	var c Config
+	c.UserSearch.Filter = "(objectClass=person)"
+	c.UserSearch.NameAttr = "sAMAccountName"
+
+	got := userBatchFilter(c, []string{"jdoe", "a(b)*"})
+	want := `(&(objectClass=person)(|(sAMAccountName=jdoe)(sAMAccountName=a\28b\29\2a)))`
+	if got != want {
+		t.Errorf("userBatchFilter() = %s, want %s", got, want)
+	}
+}
+
+func TestMatchUsers(t *testing.T) {
+	var c Config
+	c.UserSearch.NameAttr = "sAMAccountName"
+
+	entry := func(name string, uac string) *ldap.Entry {
+		return ldap.NewEntry("CN="+name+",DC=example,DC=com", map[string][]string{"sAMAccountName": {name}, "userAccountControl": {uac}})
+	}
+	entries := []*ldap.Entry{entry("JRoe", "514"), entry("jdoe", "512")}
+
+	res := matchUsers(c, []string{"jdoe", "typo", "jroe"}, entries)
+
+	want := []struct {
+		name   string
+		dn     string
+		status Status
+	}{
+		{"jdoe", "CN=jdoe,DC=example,DC=com", StatusOK},
+		{"typo", "", StatusNotFound},
+		{"JRoe", "CN=JRoe,DC=example,DC=com", StatusDisabled},
+	}
+
+	if len(res) != len(want) {
+		t.Fatalf("matchUsers() returned %d accounts, want %d", len(res), len(want))
+	}
+	for i, w := range want {
+		if res[i].Name != w.name || res[i].DN != w.dn || res[i].Status != w.status {
+			t.Errorf("account %d: %s %s %d, want %s %s %d", i, res[i].Name, res[i].DN, res[i].Status, w.name, w.dn, w.status)
+		}
+	}
+}
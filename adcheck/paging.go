@@ -0,0 +1,226 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+//pageSize is number of entries requested per page, it stays below AD default MaxPageSize of 1000
+const pageSize = 500
+
+//searchPaged runs search with simple paged results control and returns entries of all pages.
+//Timeout is refreshed before every page is requested.
+func searchPaged(ctx context.Context, conn *ldap.Conn, searchRequest *ldap.SearchRequest, phase string) (*ldap.SearchResult, error) {
+	paging := ldap.NewControlPaging(pageSize)
+	searchRequest.Controls = append(searchRequest.Controls, paging)
+	result := &ldap.SearchResult{}
+
+	for {
+		if err := setTimeout(ctx, conn, phase); err != nil {
+			return nil, err
+		}
+
+		sr, err := conn.Search(searchRequest)
+		if err != nil {
+			return nil, opError(ctx, phase, err)
+		}
+
+		result.Entries = append(result.Entries, sr.Entries...)
+		result.Referrals = append(result.Referrals, sr.Referrals...)
+
+		ctrl, ok := ldap.FindControl(sr.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+		if !ok || len(ctrl.Cookie) == 0 {
+			break
+		}
+		paging.SetCookie(ctrl.Cookie)
+	}
+
+	return result, nil
+}
+
+//groupMembers returns user members of the group, either searched by memberOf or read from group object
+func groupMembers(ctx context.Context, conn *ldap.Conn, c Config, groupDN string) ([]Account, error) {
+	if c.GroupSearch.ReadMembers {
+		dns, err := readGroupMembers(ctx, conn, c, groupDN, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		return ldapCheckDNs(ctx, conn, c, dns)
+	}
+
+	var filterMemberOf string
+	var members []Account
+
+	if c.GroupSearch.Nested {
+		filterMemberOf = fmt.Sprintf("memberOf:1.2.840.113556.1.4.1941:=%s", groupDN)
+	} else {
+		filterMemberOf = fmt.Sprintf("memberOf=%s", groupDN)
+	}
+
+	filter := fmt.Sprintf("(&%s(objectClass=user)(%s))", c.UserSearch.Filter, filterMemberOf)
+
+	c.logf("Using search filter: %s", filter)
+
+	searchRequest := ldap.NewSearchRequest(
+		c.UserSearch.BaseDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false, filter, userAttributes(c), nil,
+	)
+
+	sr, err := searchPaged(ctx, conn, searchRequest, "member search")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range sr.Entries {
+		members = append(members, userResult(c, entry))
+	}
+
+	return members, nil
+}
+
+//readGroupMembers reads member attribute of the group object using range retrieval.
+//Member groups are skipped, or expanded when nested search is enabled.
+func readGroupMembers(ctx context.Context, conn *ldap.Conn, c Config, groupDN string, seen map[string]bool) ([]string, error) {
+	var members []string
+
+	if seen[strings.ToLower(groupDN)] {
+		return nil, nil
+	}
+	seen[strings.ToLower(groupDN)] = true
+
+	values, err := rangeAttribute(ctx, conn, c, groupDN, c.GroupSearch.UserAttr)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := memberGroups(ctx, conn, c, groupDN)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dn := range values {
+		if !groups[strings.ToLower(dn)] {
+			members = append(members, dn)
+		}
+	}
+
+	if c.GroupSearch.Nested {
+		for _, dn := range values {
+			if groups[strings.ToLower(dn)] {
+				m, err := readGroupMembers(ctx, conn, c, dn, seen)
+				if err != nil {
+					return nil, err
+				}
+				members = append(members, m...)
+			}
+		}
+	}
+
+	return members, nil
+}
+
+//memberGroups returns lower-cased DNs of groups which are direct members of the group
+func memberGroups(ctx context.Context, conn *ldap.Conn, c Config, groupDN string) (map[string]bool, error) {
+	groups := map[string]bool{}
+
+	searchRequest := ldap.NewSearchRequest(
+		c.GroupSearch.BaseDN,
+		ScopeWholeSubtree, NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(&%s(memberOf=%s))", c.GroupSearch.Filter, ldap.EscapeFilter(groupDN)),
+		[]string{"dn"},
+		nil,
+	)
+
+	sr, err := searchPaged(ctx, conn, searchRequest, "group search")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range sr.Entries {
+		groups[strings.ToLower(entry.DN)] = true
+	}
+
+	return groups, nil
+}
+
+//rangeAttribute reads all values of multi-valued attribute using attr;range=low-high retrieval
+func rangeAttribute(ctx context.Context, conn *ldap.Conn, c Config, dn string, attr string) ([]string, error) {
+	var values []string
+	low := 0
+
+	for {
+		rangeAttr := fmt.Sprintf("%s;range=%d-*", attr, low)
+
+		searchRequest := ldap.NewSearchRequest(
+			dn,
+			ScopeBaseObject, NeverDerefAliases, 0, 0, false,
+			"(objectClass=*)",
+			[]string{rangeAttr},
+			nil,
+		)
+
+		if err := setTimeout(ctx, conn, "member search"); err != nil {
+			return nil, err
+		}
+
+		sr, err := conn.Search(searchRequest)
+		if err != nil {
+			return nil, opError(ctx, "member search", err)
+		}
+
+		if len(sr.Entries) == 0 {
+			return values, nil
+		}
+
+		high := "*"
+		found := false
+		for _, a := range sr.Entries[0].Attributes {
+			name := strings.ToLower(a.Name)
+			prefix := strings.ToLower(attr) + ";range="
+			if name == strings.ToLower(attr) {
+				found = true
+			} else if strings.HasPrefix(name, prefix) {
+				found = true
+				bounds := strings.SplitN(name[len(prefix):], "-", 2)
+				if len(bounds) == 2 {
+					high = bounds[1]
+				}
+			} else {
+				continue
+			}
+			values = append(values, a.Values...)
+		}
+
+		if !found || high == "*" {
+			return values, nil
+		}
+
+		next, err := strconv.Atoi(high)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s returned for %s", high, attr)
+		}
+		low = next + 1
+
+		c.logf("Retrieved %d %s values, continuing from %d", len(values), attr, low)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"time"
+)
+
+//perfCount formats number of accounts as Nagios performance data
+func perfCount(label string, n int) string {
+	return fmt.Sprintf("%s=%d;;;0", label, n)
+}
+
+//perfDays formats days left with warning and critical thresholds, state changes
+//when value drops to threshold, so OK ranges start one day above it
+func perfDays(label string, days int, warning int, critical int) string {
+	return fmt.Sprintf("%s=%d;%d:;%d:", label, days, warning+1, critical+1)
+}
+
+//perfSeconds formats duration in seconds
+func perfSeconds(label string, d time.Duration) string {
+	return fmt.Sprintf("%s=%.3fs;;;0", label, d.Seconds())
+}
+
+//Perfdata returns Nagios performance data describing the whole set, reported by every check
+func (s *AccountSet) Perfdata() []string {
+	var disabled, locked, unknown, notFound int
+
+	for _, user := range s.Accounts {
+		if s.lockedNow(user) {
+			locked++
+		}
+
+		switch user.Status {
+		case StatusDisabled:
+			disabled++
+		case StatusUnknown:
+			unknown++
+		case StatusNotFound:
+			notFound++
+		}
+	}
+
+	return []string{
+		perfCount("accounts", len(s.Accounts)),
+		perfCount("disabled", disabled),
+		perfCount("locked", locked),
+		perfCount("unknown", unknown),
+		perfCount("not_found", notFound),
+		perfCount("excluded", s.Excluded),
+		perfSeconds("time", s.Duration),
+	}
+}
+
+//lockedNow reports if account is locked. Without loaded policies lockout duration is unknown,
+//so only lockout bit of msDS-User-Account-Control-Computed is used.
+func (s *AccountSet) lockedNow(user Account) bool {
+	if !s.policies.loaded {
+		uac, err := parseUAC(user.UACComputed)
+		return err == nil && uac.Lockout()
+	}
+	locked, _, _, err := lockoutState(user, s.policies.forUser(user))
+	return err == nil && locked
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerfdataLocked(t *testing.T) {
+	now := time.Now()
+	accounts := []Account{
+		{Name: "bit", LockoutTime: fileTime(now.Add(-time.Hour)), UACComputed: "16"},
+		{Name: "expired lockout", LockoutTime: fileTime(now.Add(-time.Hour)), UACComputed: "0"},
+		{Name: "recent lockout", LockoutTime: fileTime(now.Add(-time.Minute))},
+		{Name: "missing", Status: StatusNotFound},
+	}
+
+	tests := []struct {
+		name     string
+		policies policySet
+		perf     string
+	}{
+		{"without policies", policySet{}, "locked=1;;;0"},
+		{"with policies", policySet{domain: passwordPolicy{lockoutDuration: 30 * time.Minute}, loaded: true}, "locked=2;;;0"},
+	}
+
+	for _, tt := range tests {
+		set := &AccountSet{Accounts: accounts, policies: tt.policies}
+		perf := set.Perfdata()
+		if perf[2] != tt.perf {
+			t.Errorf("%s: %s, want %s", tt.name, perf[2], tt.perf)
+		}
+	}
+}
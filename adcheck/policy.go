@@ -0,0 +1,296 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// passwordPolicy holds password and lockout settings which apply to account.
+// Zero maxPwdAge means passwords never expire, zero lockoutDuration means locked
+// accounts stay locked until administrator unlocks them.
+type passwordPolicy struct {
+	source           string
+	maxPwdAge        time.Duration
+	lockoutDuration  time.Duration
+	lockoutWindow    time.Duration
+	lockoutThreshold int
+}
+
+// policySet holds domain default policy along with fine-grained policies (PSO) indexed by DN
+type policySet struct {
+	domain passwordPolicy
+	pso    map[string]passwordPolicy
+	loaded bool
+}
+
+//forUser returns effective policy of the account, its resultant PSO or domain default
+func (p policySet) forUser(user Account) passwordPolicy {
+	if policy, ok := p.pso[strings.ToLower(user.ResultantPSO)]; ok {
+		return policy
+	}
+	return p.domain
+}
+
+//readRootDSE reads given attributes of RootDSE
+func readRootDSE(ctx context.Context, conn *ldap.Conn, attributes ...string) (*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		"",
+		ScopeBaseObject, NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		attributes,
+		nil,
+	)
+
+	if err := setTimeout(ctx, conn, "rootDSE search"); err != nil {
+		return nil, err
+	}
+
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, opError(ctx, "rootDSE search", err)
+	}
+
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("rootDSE not found")
+	}
+
+	for _, attr := range attributes {
+		if sr.Entries[0].GetAttributeValue(attr) == "" {
+			return nil, fmt.Errorf("%s not found in rootDSE", attr)
+		}
+	}
+
+	return sr.Entries[0], nil
+}
+
+//domainDN returns DN of the domain naming context read from RootDSE
+func domainDN(ctx context.Context, conn *ldap.Conn) (string, error) {
+	rootDSE, err := readRootDSE(ctx, conn, "defaultNamingContext")
+	if err != nil {
+		return "", err
+	}
+
+	return rootDSE.GetAttributeValue("defaultNamingContext"), nil
+}
+
+//loadPolicies reads domain default policy and every PSO resulting for given accounts.
+//PSO which can not be read is replaced by domain policy.
+func loadPolicies(ctx context.Context, conn *ldap.Conn, c Config, r []Account) (policySet, error) {
+	policies := policySet{pso: map[string]passwordPolicy{}}
+
+	dn, err := domainDN(ctx, conn)
+	if err != nil {
+		return policies, err
+	}
+
+	policies.domain, err = readPolicy(ctx, conn, c, dn, "maxPwdAge", "lockoutDuration", "lockOutObservationWindow", "lockoutThreshold")
+	if err != nil {
+		return policies, err
+	}
+
+	for _, user := range r {
+		key := strings.ToLower(user.ResultantPSO)
+		if key == "" {
+			continue
+		}
+		if _, ok := policies.pso[key]; ok {
+			continue
+		}
+		policy, err := readPolicy(ctx, conn, c, user.ResultantPSO, "msDS-MaximumPasswordAge", "msDS-LockoutDuration", "msDS-LockoutObservationWindow", "msDS-LockoutThreshold")
+		if err != nil {
+			if ctx.Err() != nil {
+				return policies, err
+			}
+			// PSO may be missing or unreadable by bind account, domain policy applies then
+			c.logf("Unable to read PSO %s, using domain policy: %v", user.ResultantPSO, err)
+			policy = policies.domain
+		}
+		policies.pso[key] = policy
+	}
+
+	policies.loaded = true
+	return policies, nil
+}
+
+//readPolicy reads policy attributes of domain object or PSO
+func readPolicy(ctx context.Context, conn *ldap.Conn, c Config, dn string, maxAgeAttr string, durationAttr string, windowAttr string, thresholdAttr string) (passwordPolicy, error) {
+	var policy passwordPolicy
+
+	searchRequest := ldap.NewSearchRequest(
+		dn,
+		ScopeBaseObject, NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)",
+		[]string{maxAgeAttr, durationAttr, windowAttr, thresholdAttr},
+		nil,
+	)
+
+	if err := setTimeout(ctx, conn, "policy search"); err != nil {
+		return policy, err
+	}
+
+	sr, err := conn.Search(searchRequest)
+	if err != nil {
+		return policy, opError(ctx, "policy search", err)
+	}
+
+	if len(sr.Entries) == 0 {
+		return policy, fmt.Errorf("policy object %s not found", dn)
+	}
+
+	entry := sr.Entries[0]
+	policy.source = dn
+
+	if policy.maxPwdAge, err = parseInterval(entry.GetAttributeValue(maxAgeAttr)); err != nil {
+		return policy, fmt.Errorf("unable to parse %s of %s: %v", maxAgeAttr, dn, err)
+	}
+	if policy.lockoutDuration, err = parseInterval(entry.GetAttributeValue(durationAttr)); err != nil {
+		return policy, fmt.Errorf("unable to parse %s of %s: %v", durationAttr, dn, err)
+	}
+	if policy.lockoutWindow, err = parseInterval(entry.GetAttributeValue(windowAttr)); err != nil {
+		return policy, fmt.Errorf("unable to parse %s of %s: %v", windowAttr, dn, err)
+	}
+	if threshold := entry.GetAttributeValue(thresholdAttr); threshold != "" {
+		if policy.lockoutThreshold, err = strconv.Atoi(threshold); err != nil {
+			return policy, fmt.Errorf("unable to parse %s of %s: %v", thresholdAttr, dn, err)
+		}
+	}
+
+	c.logf("Policy %s: maxPwdAge %s, lockoutDuration %s, lockoutThreshold %d", dn, policy.maxPwdAge, policy.lockoutDuration, policy.lockoutThreshold)
+
+	return policy, nil
+}
+
+//parseInterval converts AD interval (negative number of 100ns units) to duration.
+//Empty value, "never" (minimum int64) and intervals longer than duration can hold
+//are returned as zero duration.
+func parseInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if v == math.MinInt64 {
+		return 0, nil
+	}
+	if v < 0 {
+		v = -v
+	}
+	if v > math.MaxInt64/100 {
+		return 0, nil
+	}
+
+	return time.Duration(v) * 100, nil
+}
+
+//passwordExpiry returns time when account password expires, never is set if it does not expire.
+//msDS-UserPasswordExpiryTimeComputed is used if present, pwdLastSet plus maxPwdAge otherwise.
+func passwordExpiry(user Account, policy passwordPolicy) (time.Time, bool, error) {
+	if user.PasswordExpiryComputed != "" {
+		v, err := strconv.ParseInt(user.PasswordExpiryComputed, 10, 64)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if v == math.MaxInt64 {
+			return time.Time{}, true, nil
+		}
+		return fileTimeToTime(v), false, nil
+	}
+
+	if policy.maxPwdAge == 0 {
+		return time.Time{}, true, nil
+	}
+
+	v, err := strconv.ParseInt(user.PwdLastSet, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return fileTimeToTime(v).Add(policy.maxPwdAge), false, nil
+}
+
+//lockoutState reports if account is locked, when lockout started and when it clears.
+//UF_LOCKOUT bit of msDS-User-Account-Control-Computed is used if present, otherwise
+//lockoutTime plus effective lockout duration. Zero clears means manual unlock is needed.
+func lockoutState(user Account, policy passwordPolicy) (bool, time.Time, time.Time, error) {
+	var start time.Time
+	var clears time.Time
+
+	if user.LockoutTime == "" || user.LockoutTime == "0" {
+		return false, start, clears, nil
+	}
+
+	v, err := strconv.ParseInt(user.LockoutTime, 10, 64)
+	if err != nil {
+		return false, start, clears, err
+	}
+	start = fileTimeToTime(v)
+
+	if policy.lockoutDuration != 0 {
+		clears = start.Add(policy.lockoutDuration)
+	}
+
+	if user.UACComputed != "" {
+		computed, err := parseUAC(user.UACComputed)
+		if err != nil {
+			return false, start, clears, err
+		}
+		return computed.Lockout(), start, clears, nil
+	}
+
+	return clears.IsZero() || clears.After(time.Now()), start, clears, nil
+}
+
+//badPasswords returns bad password count of the account and time of last bad attempt.
+//Count is zero when last attempt is older than lockout observation window.
+func badPasswords(user Account, policy passwordPolicy) (int, time.Time, error) {
+	var last time.Time
+
+	if user.BadPwdCount == "" || user.BadPwdCount == "0" {
+		return 0, last, nil
+	}
+
+	count, err := strconv.Atoi(user.BadPwdCount)
+	if err != nil {
+		return 0, last, err
+	}
+
+	if user.BadPasswordTime != "" {
+		v, err := strconv.ParseInt(user.BadPasswordTime, 10, 64)
+		if err != nil {
+			return 0, last, err
+		}
+		last = fileTimeToTime(v)
+	}
+
+	if policy.lockoutWindow != 0 && last.Add(policy.lockoutWindow).Before(time.Now()) {
+		return 0, last, nil
+	}
+
+	return count, last, nil
+}
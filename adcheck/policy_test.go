@@ -0,0 +1,153 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+//fileTime formats time as AD FILETIME attribute value
+func fileTime(t time.Time) string {
+	return strconv.FormatInt((t.Unix()+11644473600)*10000000, 10)
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		value    string
+		duration time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"-18000000000", 30 * time.Minute},
+		{"18000000000", 30 * time.Minute},
+		{"-36288000000000", 42 * 24 * time.Hour},
+		{"-9223372036854775808", 0},
+		{"-9223372036854775807", 0},
+	}
+
+	for _, tt := range tests {
+		d, err := parseInterval(tt.value)
+		if err != nil {
+			t.Errorf("parseInterval(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if d != tt.duration {
+			t.Errorf("parseInterval(%q) = %s, want %s", tt.value, d, tt.duration)
+		}
+	}
+
+	for _, value := range []string{"never", "-1.5", "9223372036854775808"} {
+		if _, err := parseInterval(value); err == nil {
+			t.Errorf("parseInterval(%q) should fail", value)
+		}
+	}
+}
+
+func TestPasswordExpiry(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	policy := passwordPolicy{maxPwdAge: 42 * 24 * time.Hour}
+
+	tests := []struct {
+		name   string
+		user   Account
+		policy passwordPolicy
+		expiry time.Time
+		never  bool
+	}{
+		{"computed", Account{PasswordExpiryComputed: fileTime(now.Add(time.Hour)), PwdLastSet: fileTime(now)}, policy, now.Add(time.Hour), false},
+		{"computed never", Account{PasswordExpiryComputed: "9223372036854775807"}, policy, time.Time{}, true},
+		{"pwdLastSet", Account{PwdLastSet: fileTime(now)}, policy, now.Add(42 * 24 * time.Hour), false},
+		{"no max age", Account{PwdLastSet: fileTime(now)}, passwordPolicy{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		expiry, never, err := passwordExpiry(tt.user, tt.policy)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if never != tt.never || !expiry.Equal(tt.expiry) {
+			t.Errorf("%s: expiry %s, never %v, want %s, %v", tt.name, expiry, never, tt.expiry, tt.never)
+		}
+	}
+}
+
+func TestLockoutState(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	policy := passwordPolicy{lockoutDuration: 30 * time.Minute}
+
+	tests := []struct {
+		name   string
+		user   Account
+		policy passwordPolicy
+		locked bool
+		clears time.Time
+	}{
+		{"never locked", Account{}, policy, false, time.Time{}},
+		{"unlocked", Account{LockoutTime: "0"}, policy, false, time.Time{}},
+		{"locked", Account{LockoutTime: fileTime(now.Add(-10 * time.Minute))}, policy, true, now.Add(20 * time.Minute)},
+		{"lockout expired", Account{LockoutTime: fileTime(now.Add(-time.Hour))}, policy, false, now.Add(-30 * time.Minute)},
+		{"manual unlock", Account{LockoutTime: fileTime(now.Add(-time.Hour))}, passwordPolicy{}, true, time.Time{}},
+		{"computed bit set", Account{LockoutTime: fileTime(now.Add(-time.Hour)), UACComputed: "16"}, policy, true, now.Add(-30 * time.Minute)},
+		{"computed bit clear", Account{LockoutTime: fileTime(now.Add(-10 * time.Minute)), UACComputed: "0"}, policy, false, now.Add(20 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		locked, _, clears, err := lockoutState(tt.user, tt.policy)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if locked != tt.locked || !clears.Equal(tt.clears) {
+			t.Errorf("%s: locked %v, clears %s, want %v, %s", tt.name, locked, clears, tt.locked, tt.clears)
+		}
+	}
+
+	for _, user := range []Account{{LockoutTime: "abc"}, {LockoutTime: fileTime(now), UACComputed: "abc"}} {
+		if _, _, _, err := lockoutState(user, policy); err == nil {
+			t.Errorf("lockoutState(%+v) should fail", user)
+		}
+	}
+}
+
+func TestBadPasswords(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	policy := passwordPolicy{lockoutWindow: 30 * time.Minute, lockoutThreshold: 5}
+
+	tests := []struct {
+		name  string
+		user  Account
+		count int
+	}{
+		{"none", Account{BadPwdCount: "0"}, 0},
+		{"recent", Account{BadPwdCount: "3", BadPasswordTime: fileTime(now.Add(-time.Minute))}, 3},
+		{"outside window", Account{BadPwdCount: "3", BadPasswordTime: fileTime(now.Add(-time.Hour))}, 0},
+	}
+
+	for _, tt := range tests {
+		count, _, err := badPasswords(tt.user, policy)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if count != tt.count {
+			t.Errorf("%s: count %d, want %d", tt.name, count, tt.count)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+//bindPassword resolves bind password from configured source.
+//It is called only right before bind and its value is never logged.
+func bindPassword(ctx context.Context, c Config) (string, error) {
+	var password string
+
+	switch {
+	case c.BindPWFile != "":
+		c.logf("Reading bind password from file %s", c.BindPWFile)
+		data, err := ioutil.ReadFile(c.BindPWFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read bindPWFile: %v", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	case c.BindPWEnv != "":
+		c.logf("Reading bind password from environment variable %s", c.BindPWEnv)
+		value, ok := os.LookupEnv(c.BindPWEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", c.BindPWEnv)
+		}
+		password = value
+	case c.BindPWCommand != "":
+		c.logf("Reading bind password from command: %s", c.BindPWCommand)
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.BindPWCommand)
+		out, err := cmd.Output()
+		if err != nil {
+			return "", opError(ctx, "bindPWCommand", err)
+		}
+		password = strings.TrimRight(string(out), "\r\n")
+	default:
+		password = c.BindPW
+	}
+
+	if password == "" {
+		return "", fmt.Errorf("bind password is empty")
+	}
+
+	return password, nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Conditions which state can be configured, with their default states
+var conditionDefaults = map[string]State{
+	"disabled": StateCritical,
+	"locked":   StateCritical,
+	"expired":  StateCritical,
+	"notfound": StateUnknown,
+	"unknown":  StateUnknown,
+	"excluded": StateOK,
+}
+
+// Conditions which have no default state, their state follows check thresholds unless configured
+var thresholdConditions = []string{"expiring", "stale", "nearlockout"}
+
+//ParseState parses Nagios state name, eg. critical or CRITICAL
+func ParseState(name string) (State, error) {
+	for state, stateName := range stateNames {
+		if strings.EqualFold(name, stateName) {
+			return state, nil
+		}
+	}
+	return StateUnknown, fmt.Errorf("invalid state %q, must be one of OK, WARNING, CRITICAL, UNKNOWN", name)
+}
+
+//conditionNames returns names of conditions which state can be configured
+func conditionNames() []string {
+	names := append([]string{}, thresholdConditions...)
+	for name := range conditionDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//condition returns pointer to States field holding state of named condition
+func (c *Config) condition(name string) (*string, error) {
+	switch strings.ToLower(name) {
+	case "disabled":
+		return &c.States.Disabled, nil
+	case "locked":
+		return &c.States.Locked, nil
+	case "expired":
+		return &c.States.Expired, nil
+	case "expiring":
+		return &c.States.Expiring, nil
+	case "stale":
+		return &c.States.Stale, nil
+	case "nearlockout", "near_lockout", "near-lockout":
+		return &c.States.NearLockout, nil
+	case "notfound", "not_found", "not-found":
+		return &c.States.NotFound, nil
+	case "unknown":
+		return &c.States.Unknown, nil
+	case "excluded":
+		return &c.States.Excluded, nil
+	}
+	return nil, fmt.Errorf("invalid condition %q, must be one of %s", name, strings.Join(conditionNames(), ", "))
+}
+
+//SetState maps condition, eg. disabled or notfound, to state name, eg. OK
+func (c *Config) SetState(condition string, state string) error {
+	field, err := c.condition(condition)
+	if err != nil {
+		return err
+	}
+	if _, err := ParseState(state); err != nil {
+		return fmt.Errorf("%s: %v", condition, err)
+	}
+	*field = state
+	return nil
+}
+
+// stateMap holds state of each condition, threshold conditions are nil when thresholds decide
+type stateMap struct {
+	disabled    State
+	locked      State
+	expired     State
+	expiring    *State
+	stale       *State
+	nearLockout *State
+	notFound    State
+	unknown     State
+	excluded    State
+}
+
+//stateMap returns configured states of conditions, defaults are used for conditions
+//which are not configured. States are validated by Validate.
+func (c Config) stateMap() stateMap {
+	state := func(value string, condition string) State {
+		if value == "" {
+			return conditionDefaults[condition]
+		}
+		s, _ := ParseState(value)
+		return s
+	}
+
+	m := stateMap{
+		disabled: state(c.States.Disabled, "disabled"),
+		locked:   state(c.States.Locked, "locked"),
+		expired:  state(c.States.Expired, "expired"),
+		notFound: state(c.States.NotFound, "notfound"),
+		unknown:  state(c.States.Unknown, "unknown"),
+		excluded: state(c.States.Excluded, "excluded"),
+	}
+	override := func(value string) *State {
+		if value == "" {
+			return nil
+		}
+		s, _ := ParseState(value)
+		return &s
+	}
+
+	m.expiring = override(c.States.Expiring)
+	m.stale = override(c.States.Stale)
+	m.nearLockout = override(c.States.NearLockout)
+	return m
+}
+
+//orThreshold returns configured state of threshold condition, or state given by thresholds
+func orThreshold(configured *State, threshold State) State {
+	if configured != nil {
+		return *configured
+	}
+	return threshold
+}
+
+//stateErrors returns validation errors of configured states
+func (c Config) stateErrors() []string {
+	var errs []string
+	for _, name := range conditionNames() {
+		field, _ := c.condition(name)
+		if *field == "" {
+			continue
+		}
+		if _, err := ParseState(*field); err != nil {
+			errs = append(errs, fmt.Sprintf("states %s: %v!", name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,167 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state State
+	}{
+		{"OK", StateOK},
+		{"warning", StateWarning},
+		{"Critical", StateCritical},
+		{"UNKNOWN", StateUnknown},
+	}
+
+	for _, tt := range tests {
+		state, err := ParseState(tt.name)
+		if err != nil || state != tt.state {
+			t.Errorf("ParseState(%q) = %s, %v", tt.name, state, err)
+		}
+	}
+
+	for _, name := range []string{"", "crit", "2", "PENDING"} {
+		if _, err := ParseState(name); err == nil {
+			t.Errorf("ParseState(%q) should fail", name)
+		}
+	}
+}
+
+func TestSetState(t *testing.T) {
+	tests := []struct {
+		condition string
+		state     string
+		field     func(c Config) string
+	}{
+		{"disabled", "OK", func(c Config) string { return c.States.Disabled }},
+		{"LOCKED", "warning", func(c Config) string { return c.States.Locked }},
+		{"expired", "CRITICAL", func(c Config) string { return c.States.Expired }},
+		{"expiring", "OK", func(c Config) string { return c.States.Expiring }},
+		{"stale", "WARNING", func(c Config) string { return c.States.Stale }},
+		{"near-lockout", "OK", func(c Config) string { return c.States.NearLockout }},
+		{"notFound", "CRITICAL", func(c Config) string { return c.States.NotFound }},
+		{"not_found", "CRITICAL", func(c Config) string { return c.States.NotFound }},
+		{"unknown", "OK", func(c Config) string { return c.States.Unknown }},
+		{"excluded", "WARNING", func(c Config) string { return c.States.Excluded }},
+	}
+
+	for _, tt := range tests {
+		var c Config
+		if err := c.SetState(tt.condition, tt.state); err != nil {
+			t.Errorf("SetState(%q, %q) failed: %v", tt.condition, tt.state, err)
+			continue
+		}
+		if got := tt.field(c); got != tt.state {
+			t.Errorf("SetState(%q, %q) set %q", tt.condition, tt.state, got)
+		}
+	}
+
+	var c Config
+	for _, mapping := range [][2]string{{"bogus", "OK"}, {"", "OK"}, {"disabled", "meh"}, {"disabled", ""}} {
+		if err := c.SetState(mapping[0], mapping[1]); err == nil {
+			t.Errorf("SetState(%q, %q) should fail", mapping[0], mapping[1])
+		}
+	}
+	if c.States.Disabled != "" {
+		t.Errorf("invalid state was stored: %q", c.States.Disabled)
+	}
+}
+
+func TestStateMap(t *testing.T) {
+	var c Config
+	m := c.stateMap()
+	defaults := stateMap{disabled: StateCritical, locked: StateCritical, expired: StateCritical, notFound: StateUnknown, unknown: StateUnknown, excluded: StateOK}
+	if m != defaults {
+		t.Errorf("default states %+v, want %+v", m, defaults)
+	}
+
+	c.SetState("disabled", "OK")
+	c.SetState("notfound", "CRITICAL")
+	c.SetState("stale", "WARNING")
+	m = c.stateMap()
+	if m.disabled != StateOK || m.notFound != StateCritical || m.locked != StateCritical {
+		t.Errorf("configured states %+v", m)
+	}
+	if m.expiring != nil || m.nearLockout != nil || m.stale == nil || *m.stale != StateWarning {
+		t.Errorf("threshold states expiring %v, nearLockout %v, stale %v", m.expiring, m.nearLockout, m.stale)
+	}
+
+	warning := StateWarning
+	if orThreshold(nil, StateCritical) != StateCritical || orThreshold(&warning, StateCritical) != StateWarning {
+		t.Error("orThreshold does not prefer configured state")
+	}
+}
+
+func TestValidateStates(t *testing.T) {
+	var c Config
+	c.States.Locked = "sometimes"
+	errs := c.stateErrors()
+	if len(errs) != 1 {
+		t.Errorf("stateErrors() = %v, want one error", errs)
+	}
+}
+
+func TestChecksUseStates(t *testing.T) {
+	now := time.Now()
+	accounts := []Account{
+		{Name: "disabled", UACCode: "514", AccountExpires: "0", Status: StatusDisabled},
+		{Name: "typo", Status: StatusNotFound},
+		{Name: "expiring", UACCode: "512", AccountExpires: fileTime(now.Add(72 * time.Hour))},
+		{Name: "locked", UACCode: "512", AccountExpires: "0", LockoutTime: fileTime(now.Add(-time.Minute)), UACComputed: "16"},
+	}
+	excluded := []Account{{Name: "service", DN: "CN=service,OU=Service Accounts"}}
+
+	tests := []struct {
+		name   string
+		states map[string]string
+		check  Check
+		state  State
+	}{
+		{"disabled default", map[string]string{"notfound": "OK"}, Disabled{}, StateCritical},
+		{"disabled mapped to OK", map[string]string{"disabled": "OK", "notfound": "OK"}, Disabled{}, StateOK},
+		{"not found mapped to CRITICAL", map[string]string{"disabled": "OK", "notfound": "CRITICAL"}, Disabled{}, StateCritical},
+		{"not found default", map[string]string{"disabled": "OK"}, Disabled{}, StateUnknown},
+		{"locked not found", map[string]string{"locked": "OK", "notfound": "CRITICAL"}, Locked{}, StateCritical},
+		{"locked mapped to WARNING", map[string]string{"notfound": "OK", "locked": "WARNING"}, Locked{}, StateWarning},
+		{"badpwd not found", map[string]string{"notfound": "CRITICAL"}, BadPassword{Warning: 2, Critical: 1}, StateCritical},
+		{"expiring thresholds", map[string]string{"notfound": "OK"}, Expired{Warning: 10, Critical: 5}, StateCritical},
+		{"expiring mapped to OK", map[string]string{"notfound": "OK", "expiring": "OK"}, Expired{Warning: 10, Critical: 5}, StateOK},
+		{"excluded mapped to WARNING", map[string]string{"notfound": "OK", "locked": "OK", "excluded": "WARNING"}, Locked{}, StateWarning},
+	}
+
+	for _, tt := range tests {
+		var c Config
+		for condition, state := range tt.states {
+			if err := c.SetState(condition, state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		set := &AccountSet{Accounts: accounts, ExcludedAccounts: excluded, config: c}
+		res, err := tt.check.Evaluate(set)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if res.State != tt.state {
+			t.Errorf("%s: state %s, want %s (%s)", tt.name, res.State, tt.state, res.Summary)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//summary collects accounts affected by a check and counts them per label,
+//labels are reported in the order they were declared
+type summary struct {
+	labels   []string
+	counts   map[string]int
+	accounts []AccountState
+}
+
+//newSummary returns summary reporting given labels, most severe first
+func newSummary(labels ...string) *summary {
+	return &summary{labels: labels, counts: map[string]int{}}
+}
+
+//add records account in given state under label
+func (s *summary) add(label string, state State, user Account, reason string) {
+	s.counts[label]++
+	s.accounts = append(s.accounts, AccountState{Account: user, State: state, Reason: reason})
+}
+
+//addExcluded records excluded accounts when excluded state is not OK
+func (s *summary) addExcluded(set *AccountSet, states stateMap) {
+	if states.excluded == StateOK || len(set.ExcludedAccounts) == 0 {
+		return
+	}
+	s.labels = append(s.labels, "Excluded account(s)")
+	for _, user := range set.ExcludedAccounts {
+		s.add("Excluded account(s)", states.excluded, user, "excluded")
+	}
+}
+
+//result returns check result with the worst state of recorded accounts and summary
+//counting them per label, okSummary is used when no account was recorded
+func (s *summary) result(name string, okSummary string, perfdata []string) CheckResult {
+	res := CheckResult{Name: name, State: StateOK, Summary: okSummary, Perfdata: perfdata}
+
+	var sections []string
+	for _, label := range s.labels {
+		if s.counts[label] > 0 {
+			sections = append(sections, fmt.Sprintf("%s: %d", label, s.counts[label]))
+		}
+	}
+	if len(sections) > 0 {
+		res.Summary = strings.Join(sections, ", ")
+	}
+
+	for i := range s.accounts {
+		s.accounts[i].Check = name
+		res.State = Worse(res.State, s.accounts[i].State)
+	}
+
+	res.Accounts = s.accounts
+	sort.SliceStable(res.Accounts, func(i, j int) bool {
+		return stateRank[res.Accounts[i].State] > stateRank[res.Accounts[j].State]
+	})
+
+	return res
+}
@@ -0,0 +1,164 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+//fetchOptions tells which data a check needs besides account attributes
+type fetchOptions struct {
+	policies bool
+	fanOut   bool
+}
+
+// Selector tells which accounts to check. Users, Group and OU may be combined,
+// accounts in Exclude OU are skipped in Group and OU members.
+type Selector struct {
+	Users   []string
+	Group   string
+	OU      string
+	Exclude string
+}
+
+//Empty reports if no accounts are selected
+func (s Selector) Empty() bool {
+	return len(s.Users) == 0 && s.Group == "" && s.OU == ""
+}
+
+// AccountSet is accounts selected for check along with data needed to evaluate them
+type AccountSet struct {
+	Accounts []Account
+	Excluded int
+	// ExcludedAccounts are accounts removed by excluded OU, reported only when excluded state is not OK
+	ExcludedAccounts []Account
+	Duration         time.Duration
+
+	config   Config
+	policies policySet
+}
+
+//PasswordExpiry returns time when account password expires, never is true when it does not expire.
+//Password policies are loaded only for checks which need them.
+func (s *AccountSet) PasswordExpiry(user Account) (time.Time, bool, error) {
+	return passwordExpiry(user, s.policies.forUser(user))
+}
+
+//Locked reports if account is locked, with lockout start and time when lockout clears,
+//which is zero if account has to be unlocked manually
+func (s *AccountSet) Locked(user Account) (bool, time.Time, time.Time, error) {
+	return lockoutState(user, s.policies.forUser(user))
+}
+
+//loadAccounts collects selected accounts over connection and loads data needed by checks
+func loadAccounts(ctx context.Context, conn *ldap.Conn, c Config, sel Selector, opts fetchOptions) (*AccountSet, error) {
+	var err error
+	set := &AccountSet{config: c}
+
+	start := time.Now()
+
+	set.Accounts, set.ExcludedAccounts, err = collectAccounts(ctx, conn, c, sel)
+	if err != nil {
+		return nil, err
+	}
+	set.Excluded = len(set.ExcludedAccounts)
+
+	if len(set.Accounts) > 0 && opts.fanOut && c.FanOut.Enabled {
+		set.Accounts, err = fanOut(ctx, conn, c, set.Accounts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(set.Accounts) > 0 && opts.policies {
+		set.policies, err = loadPolicies(ctx, conn, c, set.Accounts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	set.Duration = time.Since(start)
+	return set, nil
+}
+
+//collectAccounts gathers accounts from users, group and OU selectors, accounts selected
+//more than once are checked only once. Excluded OU applies to group and OU members,
+//excluded accounts are returned along with the accounts.
+func collectAccounts(ctx context.Context, conn *ldap.Conn, c Config, sel Selector) ([]Account, []Account, error) {
+	var r []Account
+	var excluded []Account
+
+	if len(sel.Users) > 0 {
+		res, err := ldapCheckUsers(ctx, conn, c, sel.Users)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = append(r, res...)
+	}
+
+	if sel.Group != "" {
+		res, err := ldapCheckGroup(ctx, conn, c, sel.Group)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, removed := excludeResults(c, res, sel.Exclude)
+		r = append(r, res...)
+		excluded = append(excluded, removed...)
+	}
+
+	if sel.OU != "" {
+		res, err := ldapCheckOU(ctx, conn, c, sel.OU)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, removed := excludeResults(c, res, sel.Exclude)
+		r = append(r, res...)
+		excluded = append(excluded, removed...)
+	}
+
+	return dedupeResults(c, r), dedupeResults(c, excluded), nil
+}
+
+//Key identifies account by DN, accounts which were not found by searched name
+func (a Account) Key() string {
+	if a.DN == "" {
+		return "name:" + strings.ToLower(a.Name)
+	}
+	return "dn:" + strings.ToLower(a.DN)
+}
+
+//dedupeResults removes accounts with the same DN, not found accounts are compared by name
+func dedupeResults(c Config, r []Account) []Account {
+	var res = []Account{}
+	seen := map[string]bool{}
+
+	for _, entry := range r {
+		key := entry.Key()
+		if seen[key] {
+			c.logf("Skipping duplicate account: %s", entry.Name)
+			continue
+		}
+		seen[key] = true
+		res = append(res, entry)
+	}
+
+	return res
+}
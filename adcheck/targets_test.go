@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import "testing"
+
+func TestDedupeResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts []Account
+		want     []string
+	}{
+		{"empty", nil, nil},
+		{
+			"user and group member",
+			[]Account{
+				{Name: "jdoe", DN: "CN=John Doe,OU=Users,DC=example,DC=com"},
+				{Name: "jroe", DN: "CN=Jane Roe,OU=Users,DC=example,DC=com"},
+				{Name: "jdoe", DN: "cn=john doe,ou=users,dc=example,dc=com"},
+			},
+			[]string{"CN=John Doe,OU=Users,DC=example,DC=com", "CN=Jane Roe,OU=Users,DC=example,DC=com"},
+		},
+		{
+			"not found by name",
+			[]Account{
+				{Name: "typo", Status: StatusNotFound},
+				{Name: "TYPO", Status: StatusNotFound},
+				{Name: "other", Status: StatusNotFound},
+			},
+			[]string{"typo", "other"},
+		},
+		{
+			"same name different DN",
+			[]Account{
+				{Name: "jdoe", DN: "CN=jdoe,OU=A,DC=example,DC=com"},
+				{Name: "jdoe", DN: "CN=jdoe,OU=B,DC=example,DC=com"},
+			},
+			[]string{"CN=jdoe,OU=A,DC=example,DC=com", "CN=jdoe,OU=B,DC=example,DC=com"},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, account := range dedupeResults(Config{}, tt.accounts) {
+			if account.DN != "" {
+				got = append(got, account.DN)
+			} else {
+				got = append(got, account.Name)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestExcludeResults(t *testing.T) {
+	members := []Account{
+		{Name: "jdoe", DN: "CN=jdoe,OU=Users,DC=example,DC=com"},
+		{Name: "svc", DN: "CN=svc,OU=Service Accounts,DC=example,DC=com"},
+	}
+
+	kept, excluded := excludeResults(Config{}, members, "OU=Service Accounts")
+	if len(kept) != 1 || kept[0].Name != "jdoe" || len(excluded) != 1 || excluded[0].Name != "svc" {
+		t.Errorf("kept %+v, excluded %+v", kept, excluded)
+	}
+
+	kept, excluded = excludeResults(Config{}, members, "")
+	if len(kept) != 2 || len(excluded) != 0 {
+		t.Errorf("empty exclude: kept %d, excluded %d", len(kept), len(excluded))
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is Nagios plugin threshold range, eg. 10, 10:, ~:10, 10:20 or @10:20.
+// Value alerts when it is outside of the range, or inside of it when range starts with @.
+type Range struct {
+	Start    float64
+	End      float64
+	NoStart  bool
+	NoEnd    bool
+	Inside   bool
+	notation string
+}
+
+//ParseRange parses range in Nagios threshold syntax
+func ParseRange(s string) (Range, error) {
+	r := Range{notation: s}
+	v := strings.TrimSpace(s)
+
+	if strings.HasPrefix(v, "@") {
+		r.Inside = true
+		v = v[1:]
+	}
+
+	if v == "" {
+		return r, fmt.Errorf("invalid range %q: empty range", s)
+	}
+
+	start, end := "0", v
+	if i := strings.Index(v, ":"); i >= 0 {
+		start, end = v[:i], v[i+1:]
+		if start == "" {
+			return r, fmt.Errorf("invalid range %q: empty start, use ~ for negative infinity", s)
+		}
+	}
+
+	if start == "~" {
+		r.NoStart = true
+	} else {
+		n, err := strconv.ParseFloat(start, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+		r.Start = n
+	}
+
+	if end == "" {
+		r.NoEnd = true
+	} else {
+		n, err := strconv.ParseFloat(end, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid range %q: %v", s, err)
+		}
+		r.End = n
+	}
+
+	if r.NoStart && r.NoEnd {
+		return r, fmt.Errorf("invalid range %q: range has no bounds", s)
+	}
+
+	if !r.NoStart && !r.NoEnd && r.Start > r.End {
+		return r, fmt.Errorf("invalid range %q: start is greater than end", s)
+	}
+
+	return r, nil
+}
+
+//Alert reports if value is outside of the range, or inside of it for @ ranges
+func (r Range) Alert(v float64) bool {
+	inside := (r.NoStart || v >= r.Start) && (r.NoEnd || v <= r.End)
+	if r.Inside {
+		return inside
+	}
+	return !inside
+}
+
+//String returns range in Nagios threshold syntax
+func (r Range) String() string {
+	return r.notation
+}
+
+// Counted evaluates state of Check by number of accounts it reports in WARNING or CRITICAL state
+// instead of any single account, eg. alert only when more than 10 accounts are locked.
+// Nil Warning or Critical range never alerts. Accounts in UNKNOWN state are still reported as UNKNOWN.
+type Counted struct {
+	Check    Check
+	Warning  *Range
+	Critical *Range
+}
+
+//Name returns name of wrapped check
+func (c Counted) Name() string {
+	return c.Check.Name()
+}
+
+func (c Counted) requires() fetchOptions {
+	if r, ok := c.Check.(requirer); ok {
+		return r.requires()
+	}
+	return fetchOptions{}
+}
+
+//Evaluate evaluates wrapped check and derives its state from number of affected accounts
+func (c Counted) Evaluate(set *AccountSet) (CheckResult, error) {
+	res, err := c.Check.Evaluate(set)
+	if err != nil {
+		return res, err
+	}
+
+	affected := 0
+	unknown := false
+	for _, account := range res.Accounts {
+		switch account.State {
+		case StateWarning, StateCritical:
+			affected++
+		case StateUnknown:
+			unknown = true
+		}
+	}
+
+	res.State = StateOK
+	if c.Critical != nil && c.Critical.Alert(float64(affected)) {
+		res.State = StateCritical
+	} else if c.Warning != nil && c.Warning.Alert(float64(affected)) {
+		res.State = StateWarning
+	}
+	if unknown {
+		res.State = Worse(res.State, StateUnknown)
+	}
+
+	res.Perfdata = append(res.Perfdata, fmt.Sprintf("%s_affected=%d;%s;%s;0", res.Name, affected, rangeString(c.Warning), rangeString(c.Critical)))
+	return res, nil
+}
+
+func rangeString(r *Range) string {
+	if r == nil {
+		return ""
+	}
+	return r.String()
+}
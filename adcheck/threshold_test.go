@@ -0,0 +1,101 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		notation string
+		alerts   []float64
+		ok       []float64
+	}{
+		{"10", []float64{-1, 11}, []float64{0, 5, 10}},
+		{"10:", []float64{0, 9.5}, []float64{10, 1000}},
+		{"~:10", []float64{11}, []float64{-100, 0, 10}},
+		{"10:20", []float64{9, 21}, []float64{10, 15, 20}},
+		{"@10:20", []float64{10, 15, 20}, []float64{9, 21}},
+		{"@10", []float64{0, 10}, []float64{-1, 11}},
+		{"0", []float64{1}, []float64{0}},
+		{" 5 ", []float64{6}, []float64{5}},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRange(tt.notation)
+		if err != nil {
+			t.Errorf("ParseRange(%q) failed: %v", tt.notation, err)
+			continue
+		}
+		for _, v := range tt.alerts {
+			if !r.Alert(v) {
+				t.Errorf("%q should alert on %g", tt.notation, v)
+			}
+		}
+		for _, v := range tt.ok {
+			if r.Alert(v) {
+				t.Errorf("%q should not alert on %g", tt.notation, v)
+			}
+		}
+		if r.String() != tt.notation {
+			t.Errorf("%q String() = %q", tt.notation, r.String())
+		}
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	for _, notation := range []string{"", " ", "@", ":", "@:", ":10", "~", "~:", "abc", "10:abc", "20:10", "@20:10"} {
+		if _, err := ParseRange(notation); err == nil {
+			t.Errorf("ParseRange(%q) should fail", notation)
+		}
+	}
+}
+
+func TestCounted(t *testing.T) {
+	set := &AccountSet{Accounts: []Account{
+		{Name: "a", Status: StatusDisabled},
+		{Name: "b", Status: StatusDisabled},
+		{Name: "c", Status: StatusOK},
+	}}
+	warning, _ := ParseRange("1")
+	critical, _ := ParseRange("5")
+
+	tests := []struct {
+		check Counted
+		state State
+	}{
+		{Counted{Check: Disabled{}}, StateOK},
+		{Counted{Check: Disabled{}, Warning: &warning}, StateWarning},
+		{Counted{Check: Disabled{}, Warning: &warning, Critical: &critical}, StateWarning},
+		{Counted{Check: Disabled{}, Critical: &warning}, StateCritical},
+	}
+
+	for _, tt := range tests {
+		res, err := tt.check.Evaluate(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.State != tt.state {
+			t.Errorf("warning %v, critical %v: state %s, want %s", tt.check.Warning, tt.check.Critical, res.State, tt.state)
+		}
+	}
+
+	set.Accounts = append(set.Accounts, Account{Name: "d", Status: StatusNotFound})
+	res, _ := Counted{Check: Disabled{}, Critical: &critical}.Evaluate(set)
+	if res.State != StateUnknown {
+		t.Errorf("not found account: state %s, want UNKNOWN", res.State)
+	}
+}
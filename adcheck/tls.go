@@ -0,0 +1,99 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+// tlsVersions maps minVersion config values to crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+//useLDAPS reports if connection should be established over ldaps://
+func (c Config) useLDAPS() bool {
+	return c.LDAPS || strings.HasPrefix(strings.ToLower(c.Host), "ldaps://")
+}
+
+//ldapURL returns url to dial for given host, host may already contain the scheme
+func (c Config) ldapURL(host string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	if c.useLDAPS() {
+		return fmt.Sprintf("ldaps://%s", host)
+	}
+	return fmt.Sprintf("ldap://%s", host)
+}
+
+//tlsConfig builds TLS configuration used by ldaps:// and StartTLS connections
+func tlsConfig(c Config, host string) (*tls.Config, error) {
+	tc := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ServerName:         c.TLS.ServerName,
+	}
+
+	if tc.ServerName == "" {
+		tc.ServerName = serverName(host)
+	}
+
+	if c.TLS.MinVersion != "" {
+		tc.MinVersion = tlsVersions[c.TLS.MinVersion]
+	}
+
+	if c.TLS.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.TLS.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+
+	if c.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tc, nil
+}
+
+//serverName strips scheme and port from host, so it can be verified against server certificate
+func serverName(host string) string {
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"strconv"
+	"strings"
+)
+
+// UAC is userAccountControl attribute decoded as a bitmask.
+// Same flags are used by msDS-User-Account-Control-Computed.
+type UAC uint32
+
+// userAccountControl flags, see https://docs.microsoft.com/en-us/troubleshoot/windows-server/identity/useraccountcontrol-manipulate-account-properties
+const (
+	UACScript                     UAC = 0x0001
+	UACAccountDisable             UAC = 0x0002
+	UACHomeDirRequired            UAC = 0x0008
+	UACLockout                    UAC = 0x0010
+	UACPasswdNotRequired          UAC = 0x0020
+	UACPasswdCantChange           UAC = 0x0040
+	UACEncryptedTextPwdAllowed    UAC = 0x0080
+	UACTempDuplicateAccount       UAC = 0x0100
+	UACNormalAccount              UAC = 0x0200
+	UACInterdomainTrustAccount    UAC = 0x0800
+	UACWorkstationTrustAccount    UAC = 0x1000
+	UACServerTrustAccount         UAC = 0x2000
+	UACDontExpirePassword         UAC = 0x10000
+	UACMNSLogonAccount            UAC = 0x20000
+	UACSmartcardRequired          UAC = 0x40000
+	UACTrustedForDelegation       UAC = 0x80000
+	UACNotDelegated               UAC = 0x100000
+	UACUseDESKeyOnly              UAC = 0x200000
+	UACDontRequirePreauth         UAC = 0x400000
+	UACPasswordExpired            UAC = 0x800000
+	UACTrustedToAuthForDelegation UAC = 0x1000000
+	UACPartialSecretsAccount      UAC = 0x4000000
+)
+
+var uacNames = []struct {
+	flag UAC
+	name string
+}{
+	{UACScript, "SCRIPT"},
+	{UACAccountDisable, "ACCOUNTDISABLE"},
+	{UACHomeDirRequired, "HOMEDIR_REQUIRED"},
+	{UACLockout, "LOCKOUT"},
+	{UACPasswdNotRequired, "PASSWD_NOTREQD"},
+	{UACPasswdCantChange, "PASSWD_CANT_CHANGE"},
+	{UACEncryptedTextPwdAllowed, "ENCRYPTED_TEXT_PWD_ALLOWED"},
+	{UACTempDuplicateAccount, "TEMP_DUPLICATE_ACCOUNT"},
+	{UACNormalAccount, "NORMAL_ACCOUNT"},
+	{UACInterdomainTrustAccount, "INTERDOMAIN_TRUST_ACCOUNT"},
+	{UACWorkstationTrustAccount, "WORKSTATION_TRUST_ACCOUNT"},
+	{UACServerTrustAccount, "SERVER_TRUST_ACCOUNT"},
+	{UACDontExpirePassword, "DONT_EXPIRE_PASSWORD"},
+	{UACMNSLogonAccount, "MNS_LOGON_ACCOUNT"},
+	{UACSmartcardRequired, "SMARTCARD_REQUIRED"},
+	{UACTrustedForDelegation, "TRUSTED_FOR_DELEGATION"},
+	{UACNotDelegated, "NOT_DELEGATED"},
+	{UACUseDESKeyOnly, "USE_DES_KEY_ONLY"},
+	{UACDontRequirePreauth, "DONT_REQ_PREAUTH"},
+	{UACPasswordExpired, "PASSWORD_EXPIRED"},
+	{UACTrustedToAuthForDelegation, "TRUSTED_TO_AUTH_FOR_DELEGATION"},
+	{UACPartialSecretsAccount, "PARTIAL_SECRETS_ACCOUNT"},
+}
+
+//parseUAC decodes userAccountControl attribute value
+func parseUAC(value string) (UAC, error) {
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return UAC(v), nil
+}
+
+// Has reports if all given flags are set.
+func (u UAC) Has(flag UAC) bool {
+	return u&flag == flag
+}
+
+// Disabled reports if ACCOUNTDISABLE flag is set.
+func (u UAC) Disabled() bool {
+	return u.Has(UACAccountDisable)
+}
+
+// Lockout reports if LOCKOUT flag is set.
+func (u UAC) Lockout() bool {
+	return u.Has(UACLockout)
+}
+
+// PasswordNeverExpires reports if DONT_EXPIRE_PASSWORD flag is set.
+func (u UAC) PasswordNeverExpires() bool {
+	return u.Has(UACDontExpirePassword)
+}
+
+// PasswordExpired reports if PASSWORD_EXPIRED flag is set.
+func (u UAC) PasswordExpired() bool {
+	return u.Has(UACPasswordExpired)
+}
+
+// NormalAccount reports if NORMAL_ACCOUNT flag is set, i.e. it is a regular user account.
+func (u UAC) NormalAccount() bool {
+	return u.Has(UACNormalAccount)
+}
+
+// Flags returns names of all set flags.
+func (u UAC) Flags() []string {
+	var flags []string
+	rest := u
+	for _, f := range uacNames {
+		if u.Has(f.flag) {
+			flags = append(flags, f.name)
+			rest &^= f.flag
+		}
+	}
+	if rest != 0 {
+		flags = append(flags, "0x"+strconv.FormatUint(uint64(rest), 16))
+	}
+	return flags
+}
+
+// String returns flag names joined with |, eg. NORMAL_ACCOUNT|DONT_EXPIRE_PASSWORD.
+func (u UAC) String() string {
+	return strings.Join(u.Flags(), "|")
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adcheck
+
+import (
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestParseUAC(t *testing.T) {
+	tests := []struct {
+		value    string
+		disabled bool
+		normal   bool
+		never    bool
+		flags    string
+	}{
+		{"512", false, true, false, "NORMAL_ACCOUNT"},
+		{"514", true, true, false, "ACCOUNTDISABLE|NORMAL_ACCOUNT"},
+		{"66048", false, true, true, "NORMAL_ACCOUNT|DONT_EXPIRE_PASSWORD"},
+		{"66050", true, true, true, "ACCOUNTDISABLE|NORMAL_ACCOUNT|DONT_EXPIRE_PASSWORD"},
+		{"4096", false, false, false, "WORKSTATION_TRUST_ACCOUNT"},
+		{"8389120", false, true, false, "NORMAL_ACCOUNT|PASSWORD_EXPIRED"},
+		{"0", false, false, false, ""},
+		{"2147484160", false, true, false, "NORMAL_ACCOUNT|0x80000000"},
+	}
+
+	for _, tt := range tests {
+		uac, err := parseUAC(tt.value)
+		if err != nil {
+			t.Errorf("parseUAC(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if uac.Disabled() != tt.disabled || uac.NormalAccount() != tt.normal || uac.PasswordNeverExpires() != tt.never {
+			t.Errorf("parseUAC(%q): disabled %v, normal %v, never expires %v", tt.value, uac.Disabled(), uac.NormalAccount(), uac.PasswordNeverExpires())
+		}
+		if uac.String() != tt.flags {
+			t.Errorf("parseUAC(%q) flags %q, want %q", tt.value, uac.String(), tt.flags)
+		}
+	}
+}
+
+func TestParseUACInvalid(t *testing.T) {
+	for _, value := range []string{"", "abc", "-1", "0x200", "4294967296"} {
+		if _, err := parseUAC(value); err == nil {
+			t.Errorf("parseUAC(%q) should fail", value)
+		}
+	}
+}
+
+func TestUserResultStatus(t *testing.T) {
+	tests := []struct {
+		uac    string
+		status Status
+	}{
+		{"512", StatusOK},
+		{"514", StatusDisabled},
+		{"66050", StatusDisabled},
+		{"4096", StatusUnknown},
+		{"", StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		entry := ldap.NewEntry("CN=user,DC=example,DC=com", map[string][]string{"userAccountControl": {tt.uac}})
+		if user := userResult(Config{}, entry); user.Status != tt.status {
+			t.Errorf("userAccountControl %q: status %d, want %d", tt.uac, user.Status, tt.status)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
+)
+
+var allChecks []string
+var allExpireWarning int
+var allExpireCritical int
+var allPwExpiryWarning int
+var allPwExpiryCritical int
+var allBadPwdWarning int
+var allBadPwdCritical int
+var allStaleWarning int
+var allStaleCritical int
+var allIncludeDisabled bool
+
+// checks available to all command, in order they are reported
+var checkOrder = []string{"disabled", "locked", "expired", "pwexpiry", "badpwd", "stale"}
+
+// checkRegistry builds each check from all command flags
+var checkRegistry = map[string]func() adcheck.Check{
+	"disabled": func() adcheck.Check {
+		return adcheck.Disabled{}
+	},
+	"locked": func() adcheck.Check {
+		return adcheck.Locked{}
+	},
+	"expired": func() adcheck.Check {
+		return adcheck.Expired{Warning: allExpireWarning, Critical: allExpireCritical}
+	},
+	"pwexpiry": func() adcheck.Check {
+		return adcheck.PasswordExpiry{Warning: allPwExpiryWarning, Critical: allPwExpiryCritical}
+	},
+	"badpwd": func() adcheck.Check {
+		return adcheck.BadPassword{Warning: allBadPwdWarning, Critical: allBadPwdCritical}
+	},
+	"stale": func() adcheck.Check {
+		return adcheck.Stale{Warning: allStaleWarning, Critical: allStaleCritical, IncludeDisabled: allIncludeDisabled}
+	},
+}
+
+// allCmd represents the all command
+var allCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Run several checks against user(s) account(s) in one bind",
+	Long: `Fetch user(s) account(s) once and evaluate each of the selected checks.
+Exit state is the worst state of all checks, output has a section per check.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selected, err := selectChecks(allChecks)
+		if err != nil {
+			return err
+		}
+
+		var checks []adcheck.Check
+		for _, name := range selected {
+			checks = append(checks, checkRegistry[name]())
+		}
+
+		return runChecks(checks...)
+	},
+}
+
+//selectChecks validates requested check names and returns them in report order without duplicates
+func selectChecks(names []string) ([]string, error) {
+	requested := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := checkRegistry[name]; !ok {
+			return nil, fmt.Errorf("unknown check %q, available checks: %s", name, strings.Join(checkOrder, ", "))
+		}
+		requested[name] = true
+	}
+
+	var selected []string
+	for _, name := range checkOrder {
+		if requested[name] {
+			selected = append(selected, name)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no checks selected, available checks: %s", strings.Join(checkOrder, ", "))
+	}
+	return selected, nil
+}
+
+func init() {
+	rootCmd.AddCommand(allCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// allCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// allCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	allCmd.Flags().StringSliceVar(&allChecks, "checks", []string{"disabled", "locked", "expired"}, "Checks to run, any of "+strings.Join(checkOrder, ", "))
+	allCmd.Flags().IntVar(&allExpireWarning, "expire-warning", 14, "Trigger warning state x days before account expiry")
+	allCmd.Flags().IntVar(&allExpireCritical, "expire-critical", 7, "Trigger critical state x days before account expiry")
+	allCmd.Flags().IntVar(&allPwExpiryWarning, "pwexpiry-warning", 14, "Trigger warning state x days before password expiry")
+	allCmd.Flags().IntVar(&allPwExpiryCritical, "pwexpiry-critical", 7, "Trigger critical state x days before password expiry")
+	allCmd.Flags().IntVar(&allBadPwdWarning, "badpwd-warning", 3, "Trigger warning state x bad password attempts before lockout")
+	allCmd.Flags().IntVar(&allBadPwdCritical, "badpwd-critical", 1, "Trigger critical state x bad password attempts before lockout")
+	allCmd.Flags().IntVar(&allStaleWarning, "stale-warning", 90, "Trigger warning state if account did not log on for x days")
+	allCmd.Flags().IntVar(&allStaleCritical, "stale-critical", 180, "Trigger critical state if account did not log on for x days")
+	allCmd.Flags().BoolVar(&allIncludeDisabled, "include-disabled", false, "Check disabled accounts also in stale check")
+}
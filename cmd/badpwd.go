@@ -0,0 +1,51 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
+)
+
+var attemptsWarning int
+var attemptsCritical int
+
+// badpwdCmd represents the badpwd command
+var badpwdCmd = &cobra.Command{
+	Use:   "badpwd",
+	Short: "Check if user(s) account(s) is(are) close to lockout",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.BadPassword{Warning: attemptsWarning, Critical: attemptsCritical})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(badpwdCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// badpwdCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// badpwdCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	badpwdCmd.Flags().IntVarP(&attemptsWarning, "warning", "w", 3, "Trigger warning state x bad password attempts before lockout")
+	badpwdCmd.Flags().IntVarP(&attemptsCritical, "critical", "c", 1, "Trigger critical state x bad password attempts before lockout")
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"checkad/adcheck"
+)
+
+//flagSelector returns accounts selected by -u, -g, -o and -e flags
+func flagSelector() adcheck.Selector {
+	return adcheck.Selector{Users: users, Group: groupName, OU: ouDN, Exclude: exclude}
+}
+
+//runChecks evaluates checks against accounts selected by flags in one bind,
+//prints the report and exits with its state. Report is printed also when selected group or OU
+//has no members, selecting no accounts at all is an error.
+func runChecks(checks ...adcheck.Check) error {
+	sel := flagSelector()
+	if sel.Empty() {
+		return fmt.Errorf("no accounts selected, use -u, -g or -o")
+	}
+
+	checks, err := countedChecks(checks)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := checkContext()
+	defer cancel()
+
+	client, err := adcheck.New(config.Config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	report, err := client.Check(ctx, sel, checks...)
+	if err != nil {
+		return err
+	}
+	client.Close()
+
+	exitReport(report)
+	return nil
+}
+
+//countedChecks wraps checks with --warn-count and --crit-count thresholds when they are given
+func countedChecks(checks []adcheck.Check) ([]adcheck.Check, error) {
+	if warnCount == "" && critCount == "" {
+		return checks, nil
+	}
+
+	var warning, critical *adcheck.Range
+	if warnCount != "" {
+		r, err := adcheck.ParseRange(warnCount)
+		if err != nil {
+			return nil, fmt.Errorf("--warn-count: %v", err)
+		}
+		warning = &r
+	}
+	if critCount != "" {
+		r, err := adcheck.ParseRange(critCount)
+		if err != nil {
+			return nil, fmt.Errorf("--crit-count: %v", err)
+		}
+		critical = &r
+	}
+
+	var counted []adcheck.Check
+	for _, check := range checks {
+		counted = append(counted, adcheck.Counted{Check: check, Warning: warning, Critical: critical})
+	}
+	return counted, nil
+}
+
+//reportSummary returns summary of single check, or section per check when several were run
+func reportSummary(report *adcheck.Report) string {
+	if len(report.Checks) == 1 {
+		return report.Checks[0].Summary
+	}
+
+	var sections []string
+	for _, res := range report.Checks {
+		sections = append(sections, fmt.Sprintf("%s %s: %s", res.Name, res.State, res.Summary))
+	}
+	return strings.Join(sections, " / ")
+}
+
+//reportPerfdata returns performance data of the account set followed by data of each check
+func reportPerfdata(report *adcheck.Report) []string {
+	perfdata := report.Accounts.Perfdata()
+	for _, res := range report.Checks {
+		perfdata = append(perfdata, res.Perfdata...)
+	}
+	return perfdata
+}
+
+//exitReport prints report in Nagios multi-line format and exits with its state.
+//First line holds summary and performance data, affected accounts follow one per line.
+func exitReport(report *adcheck.Report) {
+	if outputFormat == outputJSON {
+		printJSON(report)
+		os.Exit(int(report.State))
+	}
+
+	fmt.Printf("%s: %s | %s\n", report.State, reportSummary(report), strings.Join(reportPerfdata(report), " "))
+	for _, line := range longOutput(report, maxAccounts) {
+		fmt.Println(line)
+	}
+	os.Exit(int(report.State))
+}
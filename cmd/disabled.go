@@ -18,6 +18,8 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
 )
 
 // disabledCmd represents the disabled command
@@ -26,27 +28,8 @@ var disabledCmd = &cobra.Command{
 	Short: "Check if user(s) account(s) is(are) disabled",
 	Long:  ``,
 	Args:  cobra.ExactArgs(0),
-	Run: func(cmd *cobra.Command, args []string) {
-
-		if len(users) > 0 {
-			client := ldapClient(config)
-			for _, user := range users {
-				result = append(result, ldapCheckUser(client, config, config.UserSearch.NameAttr, user)...)
-			}
-			client.Close()
-			if len(result) > 0 {
-				checkResultsDisabled(result)
-			}
-		}
-
-		if groupName != "" {
-			client := ldapClient(config)
-			result := ldapCheckGroup(client, config, groupName)
-			client.Close()
-			if len(result) > 0 {
-				checkResultsDisabled(result)
-			}
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.Disabled{})
 	},
 }
 
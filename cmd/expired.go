@@ -17,6 +17,8 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
 )
 
 var daysWarning int
@@ -27,26 +29,8 @@ var expiredCmd = &cobra.Command{
 	Use:   "expired",
 	Short: "Check if user(s) account(s) expired",
 	Long:  ``,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(users) > 0 {
-			client := ldapClient(config)
-			for _, user := range users {
-				result = append(result, ldapCheckUser(client, config, config.UserSearch.NameAttr, user)...)
-			}
-			client.Close()
-			if len(result) > 0 {
-				checkResultsExpired(result, daysWarning, daysCritical)
-			}
-		}
-
-		if groupName != "" {
-			client := ldapClient(config)
-			result := ldapCheckGroup(client, config, groupName)
-			client.Close()
-			if len(result) > 0 {
-				checkResultsExpired(result, daysWarning, daysCritical)
-			}
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.Expired{Warning: daysWarning, Critical: daysCritical})
 	},
 }
 
@@ -0,0 +1,318 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"checkad/adcheck"
+)
+
+// accountStates are states counted by checkad_accounts gauge, in exposition order
+var accountStates = []string{"total", "disabled", "locked", "expired", "expiring", "password_expired", "unknown", "not_found", "excluded"}
+
+// serveTarget is group, OU or set of users evaluated by exporter
+type serveTarget struct {
+	kind string
+	name string
+	sel  adcheck.Selector
+}
+
+// targetMetrics holds outcome of the latest evaluation of single target
+type targetMetrics struct {
+	target    serveTarget
+	success   bool
+	duration  time.Duration
+	timestamp time.Time
+	counts    map[string]int
+	accounts  []accountMetrics
+}
+
+// accountMetrics holds expiry of single account, relative to evaluation time
+type accountMetrics struct {
+	name           string
+	accountExpiry  time.Time
+	passwordExpiry time.Time
+}
+
+// exporter evaluates targets periodically over one persistent LDAP connection of adcheck client
+// and serves the latest results as Prometheus metrics
+type exporter struct {
+	client  *adcheck.Client
+	timeout int
+	targets []serveTarget
+	warning int
+
+	mu            sync.Mutex
+	results       map[string]targetMetrics
+	connectErrors int
+	searchErrors  int
+	connects      int
+}
+
+//newExporter returns exporter for given targets
+func newExporter(c Config, targets []serveTarget) (*exporter, error) {
+	client, err := adcheck.New(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exporter{
+		client:  client,
+		timeout: c.Timeout,
+		targets: targets,
+		warning: c.Serve.ExpireWarning,
+		results: map[string]targetMetrics{},
+	}, nil
+}
+
+//run evaluates all targets immediately and then every interval until ctx is done
+func (e *exporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer e.client.Close()
+
+	for {
+		e.evaluate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//evaluate evaluates every target, each one within its own timeout
+func (e *exporter) evaluate(ctx context.Context) {
+	for _, target := range e.targets {
+		if ctx.Err() != nil {
+			return
+		}
+		m := e.evaluateTarget(ctx, target)
+
+		e.mu.Lock()
+		e.results[target.kind+"/"+target.name] = m
+		e.mu.Unlock()
+	}
+}
+
+//evaluateTarget loads accounts of target and counts them per state.
+//Connection is dropped on any error and re-established for the next target.
+func (e *exporter) evaluateTarget(ctx context.Context, target serveTarget) targetMetrics {
+	m := targetMetrics{target: target, timestamp: time.Now()}
+
+	tctx := ctx
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		tctx, cancel = context.WithTimeout(ctx, time.Duration(e.timeout)*time.Second)
+		defer cancel()
+	}
+
+	connected := e.client.Connected()
+	if err := e.client.Connect(tctx); err != nil {
+		log.Printf("connect failed: %v", err)
+		e.mu.Lock()
+		e.connectErrors++
+		e.mu.Unlock()
+		m.duration = time.Since(m.timestamp)
+		return m
+	}
+
+	if !connected {
+		e.mu.Lock()
+		e.connects++
+		e.mu.Unlock()
+	}
+
+	set, err := e.client.Load(tctx, target.sel, adcheck.Locked{}, adcheck.PasswordExpiry{})
+	if err == nil {
+		m.counts, m.accounts, err = countStates(set, e.warning)
+	}
+	m.duration = time.Since(m.timestamp)
+	if err != nil {
+		log.Printf("%s %s: %v", target.kind, target.name, err)
+		e.mu.Lock()
+		e.searchErrors++
+		e.mu.Unlock()
+		return m
+	}
+
+	m.success = true
+	return m
+}
+
+//countStates counts accounts per state and collects account and password expiry times
+func countStates(set *adcheck.AccountSet, warning int) (map[string]int, []accountMetrics, error) {
+	counts := map[string]int{"total": len(set.Accounts), "excluded": set.Excluded}
+	var accounts []accountMetrics
+	now := time.Now()
+
+	for _, user := range set.Accounts {
+		switch user.Status {
+		case adcheck.StatusDisabled:
+			counts["disabled"]++
+		case adcheck.StatusUnknown:
+			counts["unknown"]++
+		case adcheck.StatusNotFound:
+			counts["not_found"]++
+			continue
+		}
+
+		locked, _, _, err := set.Locked(user)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to compute lockout state of %s: %v", user.Name, err)
+		}
+		if locked {
+			counts["locked"]++
+		}
+
+		account := accountMetrics{name: user.Name}
+
+		if expiry, ok := adcheck.FileTime(user.AccountExpires); ok {
+			account.accountExpiry = expiry
+			if !expiry.After(now) {
+				counts["expired"]++
+			} else if expiry.Before(now.AddDate(0, 0, warning)) {
+				counts["expiring"]++
+			}
+		}
+
+		if !user.UAC.PasswordNeverExpires() && user.PwdLastSet != "0" {
+			expiry, never, err := set.PasswordExpiry(user)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to compute password expiry of %s: %v", user.Name, err)
+			}
+			if !never {
+				account.passwordExpiry = expiry
+				if !expiry.After(now) {
+					counts["password_expired"]++
+				}
+			}
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return counts, accounts, nil
+}
+
+//ServeHTTP writes metrics in Prometheus text exposition format
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var keys []string
+	for key := range e.results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	metricHeader(w, "checkad_accounts", "gauge", "Number of accounts per state.")
+	for _, key := range keys {
+		m := e.results[key]
+		if !m.success {
+			continue
+		}
+		for _, state := range accountStates {
+			metric(w, "checkad_accounts", targetLabels(m.target, "state", state), float64(m.counts[state]))
+		}
+	}
+
+	metricHeader(w, "checkad_account_expiry_seconds", "gauge", "Seconds until account expires, negative when expired.")
+	for _, key := range keys {
+		m := e.results[key]
+		for _, account := range m.accounts {
+			if !account.accountExpiry.IsZero() {
+				metric(w, "checkad_account_expiry_seconds", targetLabels(m.target, "account", account.name), account.accountExpiry.Sub(time.Now()).Seconds())
+			}
+		}
+	}
+
+	metricHeader(w, "checkad_password_expiry_seconds", "gauge", "Seconds until account password expires, negative when expired.")
+	for _, key := range keys {
+		m := e.results[key]
+		for _, account := range m.accounts {
+			if !account.passwordExpiry.IsZero() {
+				metric(w, "checkad_password_expiry_seconds", targetLabels(m.target, "account", account.name), account.passwordExpiry.Sub(time.Now()).Seconds())
+			}
+		}
+	}
+
+	metricHeader(w, "checkad_scrape_duration_seconds", "gauge", "Duration of the latest evaluation of target.")
+	for _, key := range keys {
+		m := e.results[key]
+		metric(w, "checkad_scrape_duration_seconds", targetLabels(m.target), m.duration.Seconds())
+	}
+
+	metricHeader(w, "checkad_scrape_success", "gauge", "Whether the latest evaluation of target succeeded.")
+	for _, key := range keys {
+		m := e.results[key]
+		success := 0.0
+		if m.success {
+			success = 1
+		}
+		metric(w, "checkad_scrape_success", targetLabels(m.target), success)
+	}
+
+	metricHeader(w, "checkad_scrape_timestamp_seconds", "gauge", "Unix time of the latest evaluation of target.")
+	for _, key := range keys {
+		m := e.results[key]
+		metric(w, "checkad_scrape_timestamp_seconds", targetLabels(m.target), float64(m.timestamp.Unix()))
+	}
+
+	metricHeader(w, "checkad_ldap_errors_total", "counter", "Number of failed LDAP operations.")
+	metric(w, "checkad_ldap_errors_total", []string{"operation", "connect"}, float64(e.connectErrors))
+	metric(w, "checkad_ldap_errors_total", []string{"operation", "search"}, float64(e.searchErrors))
+
+	metricHeader(w, "checkad_ldap_connects_total", "counter", "Number of established LDAP connections.")
+	metric(w, "checkad_ldap_connects_total", nil, float64(e.connects))
+}
+
+//targetLabels returns type and target label pairs followed by extra pairs
+func targetLabels(target serveTarget, extra ...string) []string {
+	return append([]string{"type", target.kind, "target", target.name}, extra...)
+}
+
+func metricHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+//metric writes single sample, labels are given as name, value pairs
+func metric(w io.Writer, name string, labels []string, value float64) {
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	if len(pairs) > 0 {
+		name = name + "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+//labelEscaper escapes label values as required by text exposition format
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
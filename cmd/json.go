@@ -0,0 +1,211 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"checkad/adcheck"
+)
+
+//output formats
+const (
+	outputNagios = "nagios"
+	outputJSON   = "json"
+)
+
+// jsonReport is document printed with --output json
+type jsonReport struct {
+	State    string        `json:"state"`
+	ExitCode int           `json:"exitCode"`
+	Summary  string        `json:"summary"`
+	Error    string        `json:"error,omitempty"`
+	Checks   []jsonCheck   `json:"checks,omitempty"`
+	Accounts []jsonAccount `json:"accounts"`
+	Excluded int           `json:"excluded"`
+	Duration float64       `json:"durationSeconds"`
+	Perfdata []string      `json:"perfdata,omitempty"`
+}
+
+// jsonCheck is overall state of single check
+type jsonCheck struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+// jsonAccount is account with its attributes and state assigned by each check
+type jsonAccount struct {
+	DN              string                      `json:"dn,omitempty"`
+	Name            string                      `json:"name"`
+	UPN             string                      `json:"upn,omitempty"`
+	Found           bool                        `json:"found"`
+	Excluded        bool                        `json:"excluded,omitempty"`
+	UAC             string                      `json:"uac,omitempty"`
+	UACFlags        []string                    `json:"uacFlags,omitempty"`
+	AccountExpires  *time.Time                  `json:"accountExpires,omitempty"`
+	LockoutTime     *time.Time                  `json:"lockoutTime,omitempty"`
+	PasswordLastSet *time.Time                  `json:"passwordLastSet,omitempty"`
+	PasswordExpires *time.Time                  `json:"passwordExpires,omitempty"`
+	LastLogon       *time.Time                  `json:"lastLogon,omitempty"`
+	BadPwdCount     string                      `json:"badPwdCount,omitempty"`
+	Checks          map[string]jsonAccountState `json:"checks"`
+}
+
+// jsonAccountState is state assigned to account by a check
+type jsonAccountState struct {
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+//validOutput checks --output value
+func validOutput(format string) error {
+	switch format {
+	case outputNagios, outputJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use %s or %s", format, outputNagios, outputJSON)
+}
+
+//printJSON prints report as JSON document
+func printJSON(report *adcheck.Report) {
+	doc := jsonReport{
+		State:    report.State.String(),
+		ExitCode: int(report.State),
+		Summary:  reportSummary(report),
+		Accounts: []jsonAccount{},
+		Excluded: report.Accounts.Excluded,
+		Duration: report.Accounts.Duration.Seconds(),
+		Perfdata: reportPerfdata(report),
+	}
+
+	for _, res := range report.Checks {
+		doc.Checks = append(doc.Checks, jsonCheck{res.Name, res.State.String(), res.Summary})
+	}
+
+	index := map[string]int{}
+	for _, user := range report.Accounts.Accounts {
+		index[user.Key()] = len(doc.Accounts)
+		doc.Accounts = append(doc.Accounts, newJSONAccount(report.Accounts, user, report.Checks))
+	}
+
+	// excluded accounts are listed only when a check reported them, ie. excluded state is not OK
+	reported := map[string]bool{}
+	for _, res := range report.Checks {
+		for _, account := range res.Accounts {
+			reported[account.Account.Key()] = true
+		}
+	}
+	for _, user := range report.Accounts.ExcludedAccounts {
+		if _, ok := index[user.Key()]; ok || !reported[user.Key()] {
+			continue
+		}
+		index[user.Key()] = len(doc.Accounts)
+		account := newJSONAccount(report.Accounts, user, report.Checks)
+		account.Excluded = true
+		doc.Accounts = append(doc.Accounts, account)
+	}
+
+	for _, res := range report.Checks {
+		for _, account := range res.Accounts {
+			if i, ok := index[account.Account.Key()]; ok {
+				doc.Accounts[i].Checks[res.Name] = jsonAccountState{account.State.String(), account.Reason}
+			}
+		}
+	}
+
+	writeJSON(doc)
+}
+
+//printJSONError prints error as JSON document in UNKNOWN state
+func printJSONError(err error) {
+	writeJSON(jsonReport{
+		State:    adcheck.StateUnknown.String(),
+		ExitCode: int(adcheck.StateUnknown),
+		Summary:  err.Error(),
+		Error:    err.Error(),
+		Accounts: []jsonAccount{},
+	})
+}
+
+func writeJSON(report jsonReport) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
+
+//newJSONAccount converts account attributes, every check starts in OK state
+func newJSONAccount(set *adcheck.AccountSet, user adcheck.Account, checks []adcheck.CheckResult) jsonAccount {
+	account := jsonAccount{
+		DN:              user.DN,
+		Name:            user.Name,
+		UPN:             user.UPN,
+		Found:           user.Status != adcheck.StatusNotFound,
+		UAC:             user.UACCode,
+		UACFlags:        user.UAC.Flags(),
+		AccountExpires:  jsonTime(user.AccountExpires),
+		LockoutTime:     jsonTime(user.LockoutTime),
+		PasswordLastSet: jsonTime(user.PwdLastSet),
+		PasswordExpires: passwordExpires(set, user),
+		LastLogon:       lastLogon(user),
+		BadPwdCount:     user.BadPwdCount,
+		Checks:          map[string]jsonAccountState{},
+	}
+
+	for _, check := range checks {
+		account.Checks[check.Name] = jsonAccountState{State: adcheck.StateOK.String()}
+	}
+
+	return account
+}
+
+//jsonTime converts FILETIME attribute, unset and never values are omitted
+func jsonTime(value string) *time.Time {
+	t, ok := adcheck.FileTime(value)
+	if !ok {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+//passwordExpires returns password expiry computed by AD, or from pwdLastSet and effective policy
+//when policies were loaded. Nil if password does not expire or has to be changed at next logon.
+func passwordExpires(set *adcheck.AccountSet, user adcheck.Account) *time.Time {
+	if user.Status == adcheck.StatusNotFound || user.UAC.PasswordNeverExpires() || user.PwdLastSet == "0" {
+		return nil
+	}
+	expiry, never, err := set.PasswordExpiry(user)
+	if err != nil || never {
+		return nil
+	}
+	expiry = expiry.UTC()
+	return &expiry
+}
+
+//lastLogon returns the latest known logon of the account, nil if it never logged on
+func lastLogon(user adcheck.Account) *time.Time {
+	t, ok := user.LastLogonTime()
+	if !ok {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
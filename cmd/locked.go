@@ -17,6 +17,8 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
 )
 
 // lockedCmd represents the locked command
@@ -24,26 +26,8 @@ var lockedCmd = &cobra.Command{
 	Use:   "locked",
 	Short: "Check if user(s) account(s) is(are) locked",
 	Long:  ``,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(users) > 0 {
-			client := ldapClient(config)
-			for _, user := range users {
-				result = append(result, ldapCheckUser(client, config, config.UserSearch.NameAttr, user)...)
-			}
-			client.Close()
-			if len(result) > 0 {
-				checkResultsLocked(result)
-			}
-		}
-
-		if groupName != "" {
-			client := ldapClient(config)
-			result := ldapCheckGroup(client, config, groupName)
-			client.Close()
-			if len(result) > 0 {
-				checkResultsLocked(result)
-			}
-		}
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.Locked{})
 	},
 }
 
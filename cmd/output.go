@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"checkad/adcheck"
+)
+
+//longOutput returns one line per affected account, at most limit lines when limit is positive.
+//Lines are prefixed with check name when report combines several checks.
+func longOutput(report *adcheck.Report, limit int) []string {
+	var lines []string
+	var accounts []adcheck.AccountState
+
+	for _, res := range report.Checks {
+		accounts = append(accounts, res.Accounts...)
+	}
+
+	for i, account := range accounts {
+		if limit > 0 && i >= limit {
+			lines = append(lines, fmt.Sprintf("... and %d more account(s)", len(accounts)-limit))
+			break
+		}
+		lines = append(lines, accountLine(account, len(report.Checks) > 1))
+	}
+
+	return lines
+}
+
+//accountLine formats single affected account, not found accounts have only the searched name
+func accountLine(account adcheck.AccountState, withCheck bool) string {
+	prefix := account.State.String()
+	if withCheck {
+		prefix = account.Check + " " + prefix
+	}
+
+	user := account.Account
+	if user.DN == "" {
+		return fmt.Sprintf("%s: %s - %s", prefix, user.Name, account.Reason)
+	}
+
+	upn := user.UPN
+	if upn == "" {
+		upn = user.Name
+	}
+	return fmt.Sprintf("%s: %s (%s) - %s", prefix, user.DN, upn, account.Reason)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
+)
+
+// pwexpiryCmd represents the pwexpiry command
+var pwexpiryCmd = &cobra.Command{
+	Use:   "pwexpiry",
+	Short: "Check if user(s) password(s) expire",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.PasswordExpiry{Warning: daysWarning, Critical: daysCritical})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pwexpiryCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// pwexpiryCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// pwexpiryCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	pwexpiryCmd.Flags().IntVarP(&daysWarning, "warning", "w", 14, "Trigger warning state x days before password expiry")
+	pwexpiryCmd.Flags().IntVarP(&daysCritical, "critical", "c", 7, "Trigger critical state x days before password expiry")
+}
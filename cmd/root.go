@@ -17,9 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
+
+	"checkad/adcheck"
 
 	"github.com/spf13/cobra"
 
@@ -32,12 +37,18 @@ var VERSION string
 var cfgFile string
 var config Config
 var verbose bool
-var nested bool
 var userName string
 var groupName string
 var exclude string
-var result []Result
+var ouDN string
 var users []string
+var timeout int
+var passwordFile string
+var maxAccounts int
+var outputFormat string
+var warnCount string
+var critCount string
+var states []string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -48,6 +59,29 @@ Checkad is a Nagios plugin, it allows to check if account is in disabled/expired
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//Run: func(cmd *cobra.Command, args []string) {},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if !needsConfig(cmd) {
+			return nil
+		}
+		return initConfig(cmd)
+	},
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// skipConfig annotates commands which do not talk to LDAP and run without configuration
+const skipConfig = "checkad/skip-config"
+
+//needsConfig reports if command needs configuration, help, completion and root command do not
+func needsConfig(cmd *cobra.Command) bool {
+	if !cmd.HasParent() || cmd.Annotations[skipConfig] == "true" {
+		return false
+	}
+	switch cmd.Name() {
+	case "help", "completion", "__complete", "__completeNoDesc":
+		return false
+	}
+	return true
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -55,13 +89,29 @@ Checkad is a Nagios plugin, it allows to check if account is in disabled/expired
 func Execute(version string) {
 	VERSION = version
 	if err := rootCmd.Execute(); err != nil {
+		exitUnknown(err)
+	}
+}
 
+// checkContext returns context bounded by plugin timeout.
+func checkContext() (context.Context, context.CancelFunc) {
+	if config.Timeout <= 0 {
+		return context.WithCancel(context.Background())
 	}
+	return context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
+// exitUnknown prints error as single Nagios UNKNOWN line and exits with status 3.
+func exitUnknown(err error) {
+	if outputFormat == outputJSON {
+		printJSONError(err)
+		os.Exit(3)
+	}
+	fmt.Printf("UNKNOWN: %v\n", err)
+	os.Exit(3)
+}
 
+func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
@@ -72,15 +122,46 @@ func init() {
 	// when this action is called directly.
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose mode")
-	rootCmd.PersistentFlags().BoolVarP(&nested, "nested", "n", false, "Search nested groups also")
+	rootCmd.PersistentFlags().BoolP("nested", "n", false, "Search nested groups also")
 	rootCmd.PersistentFlags().StringSliceVarP(&users, "user", "u", []string{}, "Check user(s) account(s)")
 	rootCmd.PersistentFlags().StringVarP(&groupName, "group", "g", "", "Check all group members accounts")
+	rootCmd.PersistentFlags().StringVarP(&ouDN, "ou", "o", "", "Check all accounts in OU, eg. OU=Users,DC=example,DC=com")
 	rootCmd.PersistentFlags().StringVarP(&exclude, "exclude", "e", "", "Exclude OU, eg. OU=Service Accounts")
+	rootCmd.PersistentFlags().IntVarP(&timeout, "timeout", "t", 10, "Plugin timeout in seconds")
+	rootCmd.PersistentFlags().IntVar(&maxAccounts, "max-accounts", 0, "List at most x affected accounts in long output, 0 lists all")
+	rootCmd.PersistentFlags().StringSliceVar(&states, "state", []string{}, "Map condition to state, eg. disabled=OK,notfound=CRITICAL (disabled, locked, expired, expiring, stale, nearlockout, notfound, unknown, excluded)")
+	rootCmd.PersistentFlags().StringVar(&warnCount, "warn-count", "", "Warning range for number of affected accounts, eg. 10, 10:, ~:10, @10:20")
+	rootCmd.PersistentFlags().StringVar(&critCount, "crit-count", "", "Critical range for number of affected accounts, eg. 10, 10:, ~:10, @10:20")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputNagios, "Output format, nagios or json")
+
+	// Connection flags, they override values from config file, so checks can run without it.
+	rootCmd.PersistentFlags().StringP("host", "H", "", "LDAP host, eg. dc1.example.com:389 or ldaps://dc1.example.com")
+	rootCmd.PersistentFlags().StringP("base", "b", "", "Base DN for user and group searches")
+	rootCmd.PersistentFlags().StringP("bind-dn", "D", "", "Bind DN")
+	rootCmd.PersistentFlags().StringVarP(&passwordFile, "password-file", "P", "", "Read bind password from file")
+	rootCmd.PersistentFlags().String("filter", "", "User search filter, eg. (objectClass=person)")
+	rootCmd.PersistentFlags().Bool("all-dcs", false, "Query every domain controller for non-replicated attributes (badpwd, stale)")
+
+	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag("host", rootCmd.PersistentFlags().Lookup("host"))
+	viper.BindPFlag("userSearch.baseDN", rootCmd.PersistentFlags().Lookup("base"))
+	viper.BindPFlag("groupSearch.baseDN", rootCmd.PersistentFlags().Lookup("base"))
+	viper.BindPFlag("bindDN", rootCmd.PersistentFlags().Lookup("bind-dn"))
+	viper.BindPFlag("userSearch.filter", rootCmd.PersistentFlags().Lookup("filter"))
+	viper.BindPFlag("groupSearch.nested", rootCmd.PersistentFlags().Lookup("nested"))
+	viper.BindPFlag("fanOut.enabled", rootCmd.PersistentFlags().Lookup("all-dcs"))
+
+	viper.SetDefault("userSearch.filter", "(objectClass=person)")
+	viper.SetDefault("userSearch.nameAttr", "sAMAccountName")
+	viper.SetDefault("groupSearch.filter", "(objectClass=group)")
+	viper.SetDefault("groupSearch.userAttr", "member")
+	viper.SetDefault("groupSearch.nameAttr", "name")
 
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// initConfig reads in config file, ENV variables and connection flags if set.
+// Config file is optional when connection flags are given.
+func initConfig(cmd *cobra.Command) error {
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -88,8 +169,7 @@ func initConfig() {
 		// Find home directory.
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return err
 		}
 
 		// Search config in home directory with name ".checkad" (without extension).
@@ -110,18 +190,55 @@ func initConfig() {
 			log.Println("--> Using config file:", viper.ConfigFileUsed())
 		}
 
-		err := viper.Unmarshal(&config)
-		if err != nil {
-			fmt.Printf("unable to decode into struct, %v\n", err)
-		}
+	} else if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 
-		if err := config.Validate(); err != nil {
-			fmt.Println(err)
-			os.Exit(2)
+		if verbose {
+			log.Println("--> Config file not found, using flags")
 		}
 
 	} else {
-		fmt.Println("Config file not found!")
-		os.Exit(2)
+		return fmt.Errorf("unable to read config file: %v", err)
+	}
+
+	err := viper.Unmarshal(&config)
+	if err != nil {
+		return fmt.Errorf("unable to decode config file: %v", err)
+	}
+
+	if verbose {
+		config.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	if err := validOutput(outputFormat); err != nil {
+		return err
+	}
+
+	// states given on command line replace states from config file
+	if err := applyStates(&config.Config, states); err != nil {
+		return err
+	}
+
+	// password file given on command line replaces any password source from config file
+	if cmd.Flags().Changed("password-file") {
+		config.BindPW = ""
+		config.BindPWEnv = ""
+		config.BindPWCommand = ""
+		config.BindPWFile = passwordFile
+	}
+
+	return config.Validate()
+}
+
+//applyStates sets states given as condition=STATE mappings
+func applyStates(c *adcheck.Config, mappings []string) error {
+	for _, mapping := range mappings {
+		parts := strings.SplitN(mapping, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid --state %q, expected condition=STATE", mapping)
+		}
+		if err := c.SetState(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])); err != nil {
+			return fmt.Errorf("invalid --state: %v", err)
+		}
 	}
+	return nil
 }
@@ -0,0 +1,56 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"checkad/adcheck"
+)
+
+func TestApplyStates(t *testing.T) {
+	tests := []struct {
+		mappings []string
+		valid    bool
+	}{
+		{nil, true},
+		{[]string{"disabled=OK"}, true},
+		{[]string{"disabled=OK", "notfound=critical"}, true},
+		{[]string{" excluded = WARNING "}, true},
+		{[]string{"disabled"}, false},
+		{[]string{"disabled=meh"}, false},
+		{[]string{"bogus=OK"}, false},
+		{[]string{"=OK"}, false},
+	}
+
+	for _, tt := range tests {
+		var c adcheck.Config
+		err := applyStates(&c, tt.mappings)
+		if (err == nil) != tt.valid {
+			t.Errorf("applyStates(%q) error %v, valid %v", tt.mappings, err, tt.valid)
+		}
+	}
+
+	var c adcheck.Config
+	c.States.Disabled = "CRITICAL"
+	if err := applyStates(&c, []string{"disabled=OK", "notfound=CRITICAL"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.States.Disabled != "OK" || c.States.NotFound != "CRITICAL" {
+		t.Errorf("flags did not override config: %+v", c.States)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"checkad/adcheck"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run Prometheus exporter evaluating configured groups and users periodically",
+	Long: `Evaluate users, groups and OUs from serve section of config file (and -u, -g, -o flags)
+every interval over one persistent LDAP connection and expose results on /metrics.`,
+	Args: cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targets := serveTargets(config)
+		if len(targets) == 0 {
+			return errors.New("no targets to serve, set serve.users, serve.groups or serve.ous")
+		}
+		if config.Serve.Interval <= 0 {
+			return fmt.Errorf("invalid serve interval %d", config.Serve.Interval)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		e, err := newExporter(config, targets)
+		if err != nil {
+			return err
+		}
+		go e.run(ctx, time.Duration(config.Serve.Interval)*time.Second)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", e)
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, `<html><head><title>checkad</title></head><body><a href="/metrics">Metrics</a></body></html>`)
+		})
+
+		server := &http.Server{Addr: config.Serve.Listen, Handler: mux}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			cancel()
+			shutdown, done := context.WithTimeout(context.Background(), 5*time.Second)
+			defer done()
+			server.Shutdown(shutdown)
+		}()
+
+		log.Printf("Listening on %s, evaluating %d target(s) every %ds", config.Serve.Listen, len(targets), config.Serve.Interval)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	},
+}
+
+//serveTargets returns targets from serve section of config followed by ones given by flags,
+//users are evaluated together as single target
+func serveTargets(c Config) []serveTarget {
+	var targets []serveTarget
+
+	excluded := c.Serve.Exclude
+	if exclude != "" {
+		excluded = exclude
+	}
+
+	allUsers := append(append([]string{}, c.Serve.Users...), users...)
+	if len(allUsers) > 0 {
+		targets = append(targets, serveTarget{"users", "users", adcheck.Selector{Users: allUsers}})
+	}
+
+	groups := append([]string{}, c.Serve.Groups...)
+	if groupName != "" {
+		groups = append(groups, groupName)
+	}
+	for _, group := range groups {
+		targets = append(targets, serveTarget{"group", group, adcheck.Selector{Group: group, Exclude: excluded}})
+	}
+
+	ous := append([]string{}, c.Serve.OUs...)
+	if ouDN != "" {
+		ous = append(ous, ouDN)
+	}
+	for _, ou := range ous {
+		targets = append(targets, serveTarget{"ou", ou, adcheck.Selector{OU: ou, Exclude: excluded}})
+	}
+
+	return targets
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// serveCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// serveCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	serveCmd.Flags().String("listen", ":9725", "Address to expose metrics on")
+	serveCmd.Flags().Int("interval", 300, "Evaluate targets every x seconds")
+	serveCmd.Flags().Int("expire-warning", 14, "Count accounts expiring within x days as expiring")
+
+	viper.BindPFlag("serve.listen", serveCmd.Flags().Lookup("listen"))
+	viper.BindPFlag("serve.interval", serveCmd.Flags().Lookup("interval"))
+	viper.BindPFlag("serve.expireWarning", serveCmd.Flags().Lookup("expire-warning"))
+}
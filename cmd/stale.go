@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 Kamil Wokitajtis <wokitajtis@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"checkad/adcheck"
+)
+
+var staleWarning int
+var staleCritical int
+var includeDisabled bool
+
+// staleCmd represents the stale command
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "Check if user(s) account(s) is(are) not used",
+	Long:  ``,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChecks(adcheck.Stale{Warning: staleWarning, Critical: staleCritical, IncludeDisabled: includeDisabled})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// staleCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// staleCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	staleCmd.Flags().IntVarP(&staleWarning, "warning", "w", 90, "Trigger warning state if account did not log on for x days")
+	staleCmd.Flags().IntVarP(&staleCritical, "critical", "c", 180, "Trigger critical state if account did not log on for x days")
+	staleCmd.Flags().BoolVar(&includeDisabled, "include-disabled", false, "Check disabled accounts also")
+}
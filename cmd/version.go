@@ -30,6 +30,7 @@ and usage of using your command. For example:
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
+	Annotations: map[string]string{skipConfig: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println(VERSION)
 	},